@@ -20,7 +20,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/driver"
 	"k8s.io/klog"
 )
@@ -28,7 +30,19 @@ import (
 func main() {
 	var (
 		endpoint = flag.String("endpoint", "unix://tmp/csi.sock", "CSI Endpoint")
+		mode     = flag.String("mode", string(driver.AllMode), "Mode of the driver: controller, node or all")
 		version  = flag.Bool("version", false, "Print the version and exit")
+
+		metricsAddress = flag.String("metrics-address", "", "Address to serve Prometheus metrics on, e.g. :8080. Metrics are disabled if empty")
+		sensitiveKeys  = flag.String("sensitive-keys", strings.Join(driver.DefaultSensitiveKeys, ","), "Comma separated keys of parameters and volume context whose values are stripped from logged requests, besides the secrets")
+		extraTags      = flag.String("extra-tags", "", "Comma separated key=value tags set on every filesystem the controller creates")
+		clusterId      = flag.String("cluster-id", "", "ID of the cluster, tagged on the filesystems the controller creates. If set, the controller only deletes filesystems tagged with it")
+
+		awsMaxRetries  = flag.Int("aws-max-retries", cloud.DefaultMaxRetries, "Maximum number of retries of failed AWS API requests. Throttled requests are retried with a longer backoff")
+		awsAPIQPS      = flag.Float64("aws-api-qps", cloud.DefaultAPIQPS, "Maximum rate of AWS API requests per second, shared by all requests of the driver. 0 disables the limit")
+		awsAPIBurst    = flag.Int("aws-api-burst", cloud.DefaultAPIBurst, "Maximum burst of AWS API requests, must be positive unless aws-api-qps is 0")
+		pollInterval   = flag.Duration("poll-interval", cloud.DefaultPollInterval, "Interval of checking whether a filesystem being created is available")
+		createTimeouts = flag.String("create-timeout", cloud.DefaultCreateTimeout.String(), "Time after which a filesystem that is still being created is considered failed, optionally by deployment type, e.g. 30m,PERSISTENT_1=1h")
 	)
 	klog.InitFlags(nil)
 	flag.Parse()
@@ -42,8 +56,51 @@ func main() {
 		os.Exit(0)
 	}
 
-	drv := driver.NewDriver(*endpoint)
+	if *pollInterval <= 0 {
+		klog.Fatalln("poll-interval must be positive")
+	}
+	// a limiter with a burst of 0 never allows a request
+	if *awsAPIQPS > 0 && *awsAPIBurst <= 0 {
+		klog.Fatalln("aws-api-burst must be positive when aws-api-qps is")
+	}
+	parsedCreateTimeouts, err := cloud.ParseCreateTimeouts(*createTimeouts)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	parsedExtraTags, err := driver.ParseTags(*extraTags)
+	if err != nil {
+		klog.Fatalf("Invalid extra-tags: %v", err)
+	}
+
+	drv, err := driver.NewDriver(
+		driver.WithEndpoint(*endpoint),
+		driver.WithMode(driver.Mode(*mode)),
+		driver.WithMetricsAddress(*metricsAddress),
+		driver.WithSensitiveKeys(splitKeys(*sensitiveKeys)),
+		driver.WithExtraTags(parsedExtraTags),
+		driver.WithClusterId(*clusterId),
+		driver.WithCloudOptions(
+			cloud.WithMaxRetries(*awsMaxRetries),
+			cloud.WithRateLimit(*awsAPIQPS, *awsAPIBurst),
+			cloud.WithPollInterval(*pollInterval),
+			cloud.WithCreateTimeouts(parsedCreateTimeouts),
+		),
+	)
+	if err != nil {
+		klog.Fatalln(err)
+	}
 	if err := drv.Run(); err != nil {
 		klog.Fatalln(err)
 	}
 }
+
+// splitKeys splits comma separated keys, ignoring empty ones
+func splitKeys(s string) []string {
+	var keys []string
+	for _, key := range strings.Split(s, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -2,12 +2,15 @@ module github.com/kubernetes-sigs/aws-fsx-csi-driver
 
 require (
 	github.com/aws/aws-sdk-go v1.35.7
-	github.com/container-storage-interface/spec v1.2.0
+	github.com/container-storage-interface/spec v1.3.0
 	github.com/golang/mock v1.3.1
-	github.com/kubernetes-csi/csi-test v2.0.1+incompatible
-	github.com/onsi/ginkgo v1.10.1
-	github.com/onsi/gomega v1.7.0
-	google.golang.org/grpc v1.23.1
+	github.com/golang/protobuf v1.3.3
+	github.com/kubernetes-csi/csi-test/v4 v4.0.2
+	github.com/onsi/ginkgo v1.10.3
+	github.com/onsi/gomega v1.7.1
+	github.com/prometheus/client_golang v1.0.0
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
+	google.golang.org/grpc v1.29.1
 	k8s.io/api v0.17.0
 	k8s.io/apimachinery v0.17.0
 	k8s.io/client-go v0.17.0
@@ -17,6 +20,8 @@ require (
 )
 
 replace (
+	// etcd's clientv3, used by the e2e framework, doesn't build with newer grpc
+	google.golang.org/grpc => google.golang.org/grpc v1.26.0
 	k8s.io/api => k8s.io/api v0.17.0
 	k8s.io/apiextensions-apiserver => k8s.io/apiextensions-apiserver v0.17.0
 	k8s.io/apimachinery => k8s.io/apimachinery v0.17.1-beta.0
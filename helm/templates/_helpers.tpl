@@ -61,3 +61,14 @@ Create the name of the service account to use
     {{ default "default" .Values.serviceAccount.name }}
 {{- end -}}
 {{- end -}}
+
+{{/*
+Create the name of the service account of the node daemonset
+*/}}
+{{- define "helm.nodeServiceAccountName" -}}
+{{- if .Values.serviceAccount.create -}}
+    {{ include "helm.fullname" . }}-node
+{{- else -}}
+    {{ default "default" .Values.serviceAccount.name }}
+{{- end -}}
+{{- end -}}
@@ -20,13 +20,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/fsx"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"golang.org/x/time/rate"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog"
 )
@@ -41,6 +48,17 @@ const (
 const (
 	// VolumeNameTagKey is the key value that refers to the volume's name.
 	VolumeNameTagKey = "CSIVolumeName"
+	// SnapshotNameTagKey is the key value that refers to the snapshot's name.
+	SnapshotNameTagKey = "CSIVolumeSnapshotName"
+	// ExportOnDeleteTagKey is the key of the tag that marks filesystems to be
+	// exported to their data repository before they are deleted.
+	ExportOnDeleteTagKey = "CSIExportOnDelete"
+	// ClusterIdTagKey is the key of the tag that records the ID of the
+	// cluster whose controller created the filesystem.
+	ClusterIdTagKey = "CSIClusterId"
+	// RetainTagKey is the key of the tag that protects filesystems from
+	// being deleted by the driver unless its value is false.
+	RetainTagKey = "CSIRetain"
 )
 
 var (
@@ -54,15 +72,42 @@ var (
 
 	// ErrNotFound is returned when a resource is not found.
 	ErrNotFound = errors.New("Resource was not found")
+
+	// ErrBackupExistsDiffFs is an error that is returned if a backup
+	// exists with a given name, but was taken from a different filesystem.
+	ErrBackupExistsDiffFs = errors.New("There is already a backup with same name and different filesystem")
+
+	// ErrBackupTooSmall is returned when a filesystem is restored from a
+	// backup that is smaller than the requested capacity.
+	ErrBackupTooSmall = errors.New("Backup capacity is smaller than the requested capacity")
+
+	// ErrBackupTooLarge is returned when a filesystem is restored from a
+	// backup that is larger than the capacity limit.
+	ErrBackupTooLarge = errors.New("Backup capacity is larger than the capacity limit")
+
+	// ErrBackupIncompatible is returned when a filesystem is restored from a
+	// backup whose deployment type or storage type differs from the requested one.
+	ErrBackupIncompatible = errors.New("Backup deployment type or storage type differs from the requested one")
+
+	// ErrDataRepositoryTaskRunning is returned when a data repository task
+	// is still running.
+	ErrDataRepositoryTaskRunning = errors.New("Data repository task is still running")
 )
 
 // FileSystem represents a FSx for Lustre filesystem
 type FileSystem struct {
-	FileSystemId string
-	CapacityGiB  int64
-	DnsName      string
-	MountName    string
-	Tags         map[string]string
+	FileSystemId             string
+	CapacityGiB              int64
+	DnsName                  string
+	MountName                string
+	DeploymentType           string
+	StorageType              string
+	PerUnitStorageThroughput int64
+	Lifecycle                string
+	FailureDetails           string
+	OwnerId                  string
+	CopyTagsToBackups        bool
+	Tags                     map[string]string
 }
 
 // FileSystemOptions represents the options to create FSx for Lustre filesystem
@@ -81,38 +126,275 @@ type FileSystemOptions struct {
 	DailyAutomaticBackupStartTime string
 	AutomaticBackupRetentionDays  int64
 	CopyTagsToBackups             bool
+	BackupId                      string
+	CapacityLimitGiB              int64
+	ExportOnDelete                bool
+	// ClusterId is the ID of the cluster the filesystem is created for, if any
+	ClusterId string
+	// Tags are set on the filesystem besides the tags of the driver
+	Tags map[string]string
+}
+
+// Backup represents a FSx for Lustre user-initiated backup
+type Backup struct {
+	BackupId       string
+	FileSystemId   string
+	CapacityGiB    int64
+	DeploymentType string
+	StorageType    string
+	CreationTime   time.Time
+	Lifecycle      string
+}
+
+// BackupOptions represents the options to create FSx for Lustre backup
+type BackupOptions struct {
+	FileSystemId string
+	// Tags are set on the backup besides the tags of the driver
+	Tags map[string]string
+}
+
+// DataRepositoryTask represents a FSx for Lustre data repository task
+type DataRepositoryTask struct {
+	TaskId         string
+	FileSystemId   string
+	Lifecycle      string
+	FailureDetails string
+}
+
+// Subnet represents an EC2 subnet a filesystem can be created in
+type Subnet struct {
+	SubnetId         string
+	VpcId            string
+	AvailabilityZone string
 }
 
 // FSx abstracts FSx client to facilitate its mocking.
 // See https://docs.aws.amazon.com/sdk-for-go/api/service/fsx/ for details
 type FSx interface {
+	CreateBackupWithContext(aws.Context, *fsx.CreateBackupInput, ...request.Option) (*fsx.CreateBackupOutput, error)
+	CreateDataRepositoryTaskWithContext(aws.Context, *fsx.CreateDataRepositoryTaskInput, ...request.Option) (*fsx.CreateDataRepositoryTaskOutput, error)
 	CreateFileSystemWithContext(aws.Context, *fsx.CreateFileSystemInput, ...request.Option) (*fsx.CreateFileSystemOutput, error)
+	CreateFileSystemFromBackupWithContext(aws.Context, *fsx.CreateFileSystemFromBackupInput, ...request.Option) (*fsx.CreateFileSystemFromBackupOutput, error)
+	DeleteBackupWithContext(aws.Context, *fsx.DeleteBackupInput, ...request.Option) (*fsx.DeleteBackupOutput, error)
 	DeleteFileSystemWithContext(aws.Context, *fsx.DeleteFileSystemInput, ...request.Option) (*fsx.DeleteFileSystemOutput, error)
+	DescribeBackupsWithContext(aws.Context, *fsx.DescribeBackupsInput, ...request.Option) (*fsx.DescribeBackupsOutput, error)
+	DescribeDataRepositoryTasksWithContext(aws.Context, *fsx.DescribeDataRepositoryTasksInput, ...request.Option) (*fsx.DescribeDataRepositoryTasksOutput, error)
 	DescribeFileSystemsWithContext(aws.Context, *fsx.DescribeFileSystemsInput, ...request.Option) (*fsx.DescribeFileSystemsOutput, error)
+	UpdateFileSystemWithContext(aws.Context, *fsx.UpdateFileSystemInput, ...request.Option) (*fsx.UpdateFileSystemOutput, error)
+}
+
+// EC2 abstracts EC2 client to facilitate its mocking.
+// See https://docs.aws.amazon.com/sdk-for-go/api/service/ec2/ for details
+type EC2 interface {
+	DescribeInstancesWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.Option) (*ec2.DescribeInstancesOutput, error)
+	DescribeSecurityGroupsWithContext(aws.Context, *ec2.DescribeSecurityGroupsInput, ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeSubnetsWithContext(aws.Context, *ec2.DescribeSubnetsInput, ...request.Option) (*ec2.DescribeSubnetsOutput, error)
+}
+
+// STS abstracts STS client to facilitate its mocking.
+// See https://docs.aws.amazon.com/sdk-for-go/api/service/sts/ for details
+type STS interface {
+	GetCallerIdentityWithContext(aws.Context, *sts.GetCallerIdentityInput, ...request.Option) (*sts.GetCallerIdentityOutput, error)
 }
 
 type Cloud interface {
 	CreateFileSystem(ctx context.Context, volumeName string, fileSystemOptions *FileSystemOptions) (fs *FileSystem, err error)
 	DeleteFileSystem(ctx context.Context, fileSystemId string) (err error)
 	DescribeFileSystem(ctx context.Context, fileSystemId string) (fs *FileSystem, err error)
+	DescribeFileSystems(ctx context.Context) (fileSystems []*FileSystem, err error)
 	WaitForFileSystemAvailable(ctx context.Context, fileSystemId string) error
+	ResizeFileSystem(ctx context.Context, fileSystemId string, newSizeGiB int64) (int64, error)
+	WaitForFileSystemResize(ctx context.Context, fileSystemId string, resizeGiB int64) error
+	CreateBackup(ctx context.Context, backupName string, backupOptions *BackupOptions) (backup *Backup, err error)
+	DeleteBackup(ctx context.Context, backupId string) (err error)
+	DescribeBackup(ctx context.Context, backupId string) (backup *Backup, err error)
+	DescribeBackups(ctx context.Context, fileSystemId string) (backups []*Backup, err error)
+	CreateExportTask(ctx context.Context, fileSystemId string) (task *DataRepositoryTask, err error)
+	WaitForDataRepositoryTask(ctx context.Context, taskId string) error
+	DescribeSubnets(ctx context.Context, subnetIds []string) (subnets []*Subnet, err error)
+	FindSubnets(ctx context.Context, vpcId string, tags map[string]string) (subnets []*Subnet, err error)
+	FindSecurityGroups(ctx context.Context, vpcId string, tags map[string]string, names []string) (securityGroupIds []string, err error)
+	GetInstanceVpcId(ctx context.Context, instanceId string) (vpcId string, err error)
+	GetAccountId(ctx context.Context) (accountId string, err error)
 }
 
+const (
+	// DefaultMaxRetries is the default number of retries of failed AWS requests
+	DefaultMaxRetries = 8
+	// DefaultAPIQPS is the default rate of AWS requests per second
+	DefaultAPIQPS = 10
+	// DefaultAPIBurst is the default burst of AWS requests
+	DefaultAPIBurst = 20
+	// DefaultPollInterval is the default interval of checking whether a
+	// filesystem being created is available
+	DefaultPollInterval = 15 * time.Second
+	// DefaultCreateTimeout is the default time after which a filesystem that
+	// is still being created is considered stuck
+	DefaultCreateTimeout = 30 * time.Minute
+
+	// assumeRoleSessionName is the name of the sessions of assumed roles,
+	// which identifies the driver in CloudTrail
+	assumeRoleSessionName = "aws-fsx-csi-driver"
+)
+
 type cloud struct {
 	fsx FSx
+	ec2 EC2
+	sts STS
+
+	pollInterval   time.Duration
+	createTimeouts map[string]time.Duration
+}
+
+// CloudOptions holds the options of the cloud set by NewCloud's options
+type CloudOptions struct {
+	maxRetries     int
+	limiter        *rate.Limiter
+	pollInterval   time.Duration
+	createTimeouts map[string]time.Duration
+	credentials    *Credentials
+}
+
+// Credentials are the AWS credentials a cloud makes requests with instead of
+// the credentials of the controller. The static keys, or the credentials of
+// the controller if there are none, are used to assume the role if set.
+type Credentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	RoleArn         string
+	ExternalId      string
+}
+
+// WithMaxRetries sets the number of retries of failed AWS requests
+func WithMaxRetries(maxRetries int) func(*CloudOptions) {
+	return func(o *CloudOptions) {
+		o.maxRetries = maxRetries
+	}
+}
+
+// WithRateLimit sets the rate and burst of AWS requests. A rate of 0 disables
+// the limit. The limiter is created along with the option, so it is shared by
+// all the clouds created with the option, e.g. the clouds of every account.
+func WithRateLimit(qps float64, burst int) func(*CloudOptions) {
+	limiter := newRateLimiter(qps, burst)
+	return func(o *CloudOptions) {
+		o.limiter = limiter
+	}
+}
+
+func newRateLimiter(qps float64, burst int) *rate.Limiter {
+	limit := rate.Limit(qps)
+	if qps <= 0 {
+		limit = rate.Inf
+	}
+	return rate.NewLimiter(limit, burst)
+}
+
+// WithPollInterval sets the interval of checking whether a filesystem being
+// created is available
+func WithPollInterval(pollInterval time.Duration) func(*CloudOptions) {
+	return func(o *CloudOptions) {
+		o.pollInterval = pollInterval
+	}
+}
+
+// WithCreateTimeouts sets the time after which a filesystem that is still
+// being created is considered stuck, by deployment type. The timeout of the
+// empty deployment type applies to the deployment types not listed.
+func WithCreateTimeouts(createTimeouts map[string]time.Duration) func(*CloudOptions) {
+	return func(o *CloudOptions) {
+		o.createTimeouts = createTimeouts
+	}
+}
+
+// WithCredentials sets the credentials the cloud makes requests with
+func WithCredentials(c *Credentials) func(*CloudOptions) {
+	return func(o *CloudOptions) {
+		o.credentials = c
+	}
 }
 
 // NewCloud returns a new instance of AWS cloud
 // It panics if session is invalid
-func NewCloud(region string) Cloud {
+func NewCloud(region string, options ...func(*CloudOptions)) Cloud {
+	cloudOptions := CloudOptions{
+		maxRetries:   DefaultMaxRetries,
+		pollInterval: DefaultPollInterval,
+	}
+	for _, option := range options {
+		option(&cloudOptions)
+	}
+	if cloudOptions.limiter == nil {
+		cloudOptions.limiter = newRateLimiter(DefaultAPIQPS, DefaultAPIBurst)
+	}
+
 	awsConfig := &aws.Config{
 		Region:                        aws.String(region),
 		CredentialsChainVerboseErrors: aws.Bool(true),
 	}
+	request.WithRetryer(awsConfig, newRetryer(cloudOptions.maxRetries))
+	c := cloudOptions.credentials
+	if c != nil && c.AccessKeyId != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(c.AccessKeyId, c.SecretAccessKey, c.SessionToken)
+	}
+
+	sess := session.Must(session.NewSession(awsConfig))
+	if c != nil && c.RoleArn != "" {
+		sess = sess.Copy(&aws.Config{
+			Credentials: stscreds.NewCredentials(sess, c.RoleArn, func(p *stscreds.AssumeRoleProvider) {
+				p.RoleSessionName = assumeRoleSessionName
+				if c.ExternalId != "" {
+					p.ExternalID = aws.String(c.ExternalId)
+				}
+			}),
+		})
+	}
+	// the limiter is shared by the FSx and EC2 clients, and applies to
+	// retries as well, so that throttling isn't made worse by retrying
+	sess.Handlers.Sign.PushFrontNamed(newRateLimitHandler(cloudOptions.limiter))
+	sess.Handlers.Complete.PushBackNamed(newMetricsHandler())
 
 	return &cloud{
-		fsx: fsx.New(session.Must(session.NewSession(awsConfig))),
+		fsx:            fsx.New(sess),
+		ec2:            ec2.New(sess),
+		sts:            sts.New(sess),
+		pollInterval:   cloudOptions.pollInterval,
+		createTimeouts: cloudOptions.createTimeouts,
+	}
+}
+
+// ParseCreateTimeouts parses create timeouts of the form
+// "30m,PERSISTENT_1=1h", where a timeout without a deployment type applies to
+// the deployment types not listed.
+func ParseCreateTimeouts(s string) (map[string]time.Duration, error) {
+	createTimeouts := map[string]time.Duration{}
+	if len(s) == 0 {
+		return createTimeouts, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		deploymentType := ""
+		value := entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			deploymentType = strings.TrimSpace(entry[:i])
+			value = entry[i+1:]
+			if len(deploymentType) == 0 {
+				return nil, fmt.Errorf("deployment type of create timeout %q is empty", entry)
+			}
+		}
+		if _, ok := createTimeouts[deploymentType]; ok {
+			return nil, fmt.Errorf("create timeout of deployment type %q is set more than once", deploymentType)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid create timeout %q: %v", entry, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("create timeout %q must be positive", entry)
+		}
+		createTimeouts[deploymentType] = d
 	}
+	return createTimeouts, nil
 }
 
 func (c *cloud) CreateFileSystem(ctx context.Context, volumeName string, fileSystemOptions *FileSystemOptions) (fs *FileSystem, err error) {
@@ -157,48 +439,119 @@ func (c *cloud) CreateFileSystem(ctx context.Context, volumeName string, fileSys
 		lustreConfiguration.SetCopyTagsToBackups(true)
 	}
 
-	input := &fsx.CreateFileSystemInput{
+	tags := []*fsx.Tag{
+		{
+			Key:   aws.String(VolumeNameTagKey),
+			Value: aws.String(volumeName),
+		},
+	}
+	if fileSystemOptions.ExportOnDelete {
+		tags = append(tags, &fsx.Tag{
+			Key:   aws.String(ExportOnDeleteTagKey),
+			Value: aws.String("true"),
+		})
+	}
+	if fileSystemOptions.ClusterId != "" {
+		tags = append(tags, &fsx.Tag{
+			Key:   aws.String(ClusterIdTagKey),
+			Value: aws.String(fileSystemOptions.ClusterId),
+		})
+	}
+	tags = append(tags, mapToTags(fileSystemOptions.Tags)...)
+
+	var fileSystem *fsx.FileSystem
+	if fileSystemOptions.BackupId != "" {
+		fileSystem, err = c.createFileSystemFromBackup(ctx, volumeName, fileSystemOptions, lustreConfiguration, tags)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		input := &fsx.CreateFileSystemInput{
+			ClientRequestToken:  aws.String(volumeName),
+			FileSystemType:      aws.String("LUSTRE"),
+			LustreConfiguration: lustreConfiguration,
+			StorageCapacity:     aws.Int64(fileSystemOptions.CapacityGiB),
+			SubnetIds:           []*string{aws.String(fileSystemOptions.SubnetId)},
+			SecurityGroupIds:    aws.StringSlice(fileSystemOptions.SecurityGroupIds),
+			Tags:                tags,
+		}
+
+		if fileSystemOptions.StorageType != "" {
+			input.StorageType = aws.String(fileSystemOptions.StorageType)
+		}
+		if fileSystemOptions.KmsKeyId != "" {
+			input.KmsKeyId = aws.String(fileSystemOptions.KmsKeyId)
+		}
+
+		output, err := c.fsx.CreateFileSystemWithContext(ctx, input)
+		if err != nil {
+			if isIncompatibleParameter(err) {
+				return nil, ErrFsExistsDiffSize
+			}
+			return nil, fmt.Errorf("CreateFileSystem failed: %v", err)
+		}
+		fileSystem = output.FileSystem
+	}
+
+	return newFileSystem(fileSystem), nil
+}
+
+// createFileSystemFromBackup restores a new filesystem from a backup. The
+// capacity of the restored filesystem is the capacity of the backup, so the
+// backup must be at least as large as requested and, if a capacity limit is
+// set, not larger than the limit, and it must have been taken
+// from a filesystem of the requested deployment type and storage type.
+// Like a new filesystem, whose requested capacity is rounded up to the
+// granularity of its deployment type even beyond the limit, a backup of
+// the rounded up capacity is not too large.
+func (c *cloud) createFileSystemFromBackup(ctx context.Context, volumeName string, fileSystemOptions *FileSystemOptions, lustreConfiguration *fsx.CreateFileSystemLustreConfiguration, tags []*fsx.Tag) (*fsx.FileSystem, error) {
+	backup, err := c.getBackup(ctx, fileSystemOptions.BackupId)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("DescribeBackups failed: %v", err)
+	}
+
+	b := newBackup(backup)
+	if b.CapacityGiB < fileSystemOptions.CapacityGiB {
+		return nil, ErrBackupTooSmall
+	}
+	if fileSystemOptions.CapacityLimitGiB > 0 && b.CapacityGiB > fileSystemOptions.CapacityLimitGiB && b.CapacityGiB > fileSystemOptions.CapacityGiB {
+		return nil, ErrBackupTooLarge
+	}
+	if fileSystemOptions.DeploymentType != "" && fileSystemOptions.DeploymentType != b.DeploymentType {
+		return nil, ErrBackupIncompatible
+	}
+	if fileSystemOptions.StorageType != "" && fileSystemOptions.StorageType != b.StorageType {
+		return nil, ErrBackupIncompatible
+	}
+
+	input := &fsx.CreateFileSystemFromBackupInput{
+		BackupId:            aws.String(fileSystemOptions.BackupId),
 		ClientRequestToken:  aws.String(volumeName),
-		FileSystemType:      aws.String("LUSTRE"),
 		LustreConfiguration: lustreConfiguration,
-		StorageCapacity:     aws.Int64(fileSystemOptions.CapacityGiB),
 		SubnetIds:           []*string{aws.String(fileSystemOptions.SubnetId)},
 		SecurityGroupIds:    aws.StringSlice(fileSystemOptions.SecurityGroupIds),
-		Tags: []*fsx.Tag{
-			{
-				Key:   aws.String(VolumeNameTagKey),
-				Value: aws.String(volumeName),
-			},
-		},
+		Tags:                tags,
 	}
 
 	if fileSystemOptions.StorageType != "" {
 		input.StorageType = aws.String(fileSystemOptions.StorageType)
 	}
-	if fileSystemOptions.KmsKeyId != "" {
-		input.KmsKeyId = aws.String(fileSystemOptions.KmsKeyId)
-	}
 
-	output, err := c.fsx.CreateFileSystemWithContext(ctx, input)
+	output, err := c.fsx.CreateFileSystemFromBackupWithContext(ctx, input)
 	if err != nil {
 		if isIncompatibleParameter(err) {
 			return nil, ErrFsExistsDiffSize
 		}
-		return nil, fmt.Errorf("CreateFileSystem failed: %v", err)
-	}
-
-	mountName := "fsx"
-	if output.FileSystem.LustreConfiguration.MountName != nil {
-		mountName = *output.FileSystem.LustreConfiguration.MountName
+		if isBackupNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("CreateFileSystemFromBackup failed: %v", err)
 	}
 
-	return &FileSystem{
-		FileSystemId: *output.FileSystem.FileSystemId,
-		CapacityGiB:  *output.FileSystem.StorageCapacity,
-		DnsName:      *output.FileSystem.DNSName,
-		MountName:    mountName,
-		Tags:         tagsToMap(output.FileSystem.Tags),
-	}, nil
+	return output.FileSystem, nil
 }
 
 func (c *cloud) DeleteFileSystem(ctx context.Context, fileSystemId string) (err error) {
@@ -222,35 +575,97 @@ func tagsToMap(tagList []*fsx.Tag) map[string]string {
 	return tagMap
 }
 
+// mapToTags returns the tags of the map sorted by key, so that the requests
+// of retries are identical
+func mapToTags(tagMap map[string]string) []*fsx.Tag {
+	keys := make([]string, 0, len(tagMap))
+	for key := range tagMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var tagList []*fsx.Tag
+	for _, key := range keys {
+		tagList = append(tagList, &fsx.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tagMap[key]),
+		})
+	}
+	return tagList
+}
+
 func (c *cloud) DescribeFileSystem(ctx context.Context, fileSystemId string) (*FileSystem, error) {
 	fs, err := c.getFileSystem(ctx, fileSystemId)
 	if err != nil {
 		return nil, err
 	}
 
-	mountName := "fsx"
-	if fs.LustreConfiguration.MountName != nil {
-		mountName = *fs.LustreConfiguration.MountName
+	return newFileSystem(fs), nil
+}
+
+// DescribeFileSystems returns all the FSx for Lustre filesystems of the account
+// in the region, following NextToken across pages.
+func (c *cloud) DescribeFileSystems(ctx context.Context) ([]*FileSystem, error) {
+	input := &fsx.DescribeFileSystemsInput{}
+
+	var fileSystems []*FileSystem
+	for {
+		output, err := c.fsx.DescribeFileSystemsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeFileSystems failed: %v", err)
+		}
+		for _, fs := range output.FileSystems {
+			if aws.StringValue(fs.FileSystemType) != fsx.FileSystemTypeLustre {
+				continue
+			}
+			fileSystems = append(fileSystems, newFileSystem(fs))
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	return fileSystems, nil
+}
+
+func newFileSystem(fs *fsx.FileSystem) *FileSystem {
+	failureDetails := ""
+	if fs.FailureDetails != nil {
+		failureDetails = aws.StringValue(fs.FailureDetails.Message)
+	}
+
+	fileSystem := &FileSystem{
+		FileSystemId:   aws.StringValue(fs.FileSystemId),
+		CapacityGiB:    aws.Int64Value(fs.StorageCapacity),
+		DnsName:        aws.StringValue(fs.DNSName),
+		MountName:      "fsx",
+		StorageType:    aws.StringValue(fs.StorageType),
+		Lifecycle:      aws.StringValue(fs.Lifecycle),
+		FailureDetails: failureDetails,
+		OwnerId:        aws.StringValue(fs.OwnerId),
+		Tags:           tagsToMap(fs.Tags),
 	}
 
-	return &FileSystem{
-		FileSystemId: *fs.FileSystemId,
-		CapacityGiB:  *fs.StorageCapacity,
-		DnsName:      *fs.DNSName,
-		MountName:    mountName,
-		Tags:         tagsToMap(fs.Tags),
-	}, nil
+	// Filesystems that are still being created, or failed to be created,
+	// may lack the Lustre configuration.
+	if lustre := fs.LustreConfiguration; lustre != nil {
+		if lustre.MountName != nil {
+			fileSystem.MountName = aws.StringValue(lustre.MountName)
+		}
+		fileSystem.DeploymentType = aws.StringValue(lustre.DeploymentType)
+		fileSystem.PerUnitStorageThroughput = aws.Int64Value(lustre.PerUnitStorageThroughput)
+		fileSystem.CopyTagsToBackups = aws.BoolValue(lustre.CopyTagsToBackups)
+	}
+	return fileSystem
 }
 
+// WaitForFileSystemAvailable waits until the filesystem is available, or
+// until ctx is done. It fails if the filesystem has been being created for
+// longer than the create timeout of its deployment type.
 func (c *cloud) WaitForFileSystemAvailable(ctx context.Context, fileSystemId string) error {
-	var (
-		// interval to check if filesystem is ready
-		// needs to be shorter than the provisioner timeout
-		checkInterval = 15 * time.Second
-		// FSx for lustre filesystem creation time is around 5 mins
-		checkTimeout = 7 * time.Minute
-	)
-	err := wait.Poll(checkInterval, checkTimeout, func() (done bool, err error) {
+	fsWait := startFileSystemWait(fileSystemId)
+	err := wait.PollImmediateUntil(c.pollInterval, func() (done bool, err error) {
+		fsWait.update()
 		fs, err := c.getFileSystem(ctx, fileSystemId)
 		if err != nil {
 			return true, err
@@ -260,14 +675,489 @@ func (c *cloud) WaitForFileSystemAvailable(ctx context.Context, fileSystemId str
 		case "AVAILABLE":
 			return true, nil
 		case "CREATING":
+			createTimeout := c.getCreateTimeout(fs)
+			if fs.CreationTime != nil && time.Since(*fs.CreationTime) > createTimeout {
+				return true, fmt.Errorf("filesystem %s is still being created after %v", fileSystemId, createTimeout)
+			}
 			return false, nil
 		default:
 			return true, fmt.Errorf("unexpected state for filesystem %s: %q", fileSystemId, *fs.Lifecycle)
 		}
+	}, ctx.Done())
+	fsWait.done(err)
+
+	return err
+
+}
+
+func (c *cloud) getCreateTimeout(fs *fsx.FileSystem) time.Duration {
+	deploymentType := ""
+	if fs.LustreConfiguration != nil {
+		deploymentType = aws.StringValue(fs.LustreConfiguration.DeploymentType)
+	}
+	if createTimeout, ok := c.createTimeouts[deploymentType]; ok {
+		return createTimeout
+	}
+	if createTimeout, ok := c.createTimeouts[""]; ok {
+		return createTimeout
+	}
+	return DefaultCreateTimeout
+}
+
+// ResizeFileSystem requests the storage capacity of the filesystem to be
+// increased to newSizeGiB, and returns the capacity being resized to. If an
+// update to that capacity is already in progress, no new request is made.
+func (c *cloud) ResizeFileSystem(ctx context.Context, fileSystemId string, newSizeGiB int64) (int64, error) {
+	fs, err := c.getFileSystem(ctx, fileSystemId)
+	if err != nil {
+		return 0, err
+	}
+
+	action := getUpdateAction(fs, newSizeGiB)
+	if action != nil && aws.StringValue(action.Status) != "FAILED" {
+		klog.V(4).Infof("ResizeFileSystem: update of filesystem %s to %d GiB is already %s", fileSystemId, newSizeGiB, aws.StringValue(action.Status))
+		return newSizeGiB, nil
+	}
+
+	input := &fsx.UpdateFileSystemInput{
+		FileSystemId:    aws.String(fileSystemId),
+		StorageCapacity: aws.Int64(newSizeGiB),
+	}
+	if _, err = c.fsx.UpdateFileSystemWithContext(ctx, input); err != nil {
+		if isFileSystemNotFound(err) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("UpdateFileSystem failed: %v", err)
+	}
+
+	return newSizeGiB, nil
+}
+
+// WaitForFileSystemResize waits until the update of the filesystem to
+// resizeGiB is completed, including the storage optimization that follows the
+// increase of the storage capacity.
+func (c *cloud) WaitForFileSystemResize(ctx context.Context, fileSystemId string, resizeGiB int64) error {
+	var (
+		checkInterval = 30 * time.Second
+		stopCh        = ctx.Done()
+	)
+	err := wait.PollImmediateUntil(checkInterval, func() (done bool, err error) {
+		fs, err := c.getFileSystem(ctx, fileSystemId)
+		if err != nil {
+			return true, err
+		}
+		action := getUpdateAction(fs, resizeGiB)
+		if action == nil {
+			if aws.Int64Value(fs.StorageCapacity) >= resizeGiB {
+				return true, nil
+			}
+			return true, fmt.Errorf("no update of filesystem %s to %d GiB found", fileSystemId, resizeGiB)
+		}
+		klog.V(4).Infof("WaitForFileSystemResize filesystem update status is: %v", aws.StringValue(action.Status))
+		switch aws.StringValue(action.Status) {
+		case "COMPLETED":
+			return true, nil
+		case "PENDING", "IN_PROGRESS", "UPDATED_OPTIMIZING":
+			return false, nil
+		case "FAILED":
+			message := ""
+			if action.FailureDetails != nil {
+				message = aws.StringValue(action.FailureDetails.Message)
+			}
+			return true, fmt.Errorf("update of filesystem %s to %d GiB failed: %s", fileSystemId, resizeGiB, message)
+		default:
+			return true, fmt.Errorf("unexpected status for update of filesystem %s: %q", fileSystemId, aws.StringValue(action.Status))
+		}
+	}, stopCh)
+
+	return err
+}
+
+// getUpdateAction returns the most recent administrative action that updates
+// the storage capacity of the filesystem to resizeGiB, or nil if there is none.
+func getUpdateAction(fs *fsx.FileSystem, resizeGiB int64) *fsx.AdministrativeAction {
+	var latest *fsx.AdministrativeAction
+	for _, action := range fs.AdministrativeActions {
+		if aws.StringValue(action.AdministrativeActionType) != "FILE_SYSTEM_UPDATE" {
+			continue
+		}
+		if action.TargetFileSystemValues == nil || aws.Int64Value(action.TargetFileSystemValues.StorageCapacity) != resizeGiB {
+			continue
+		}
+		if latest == nil || aws.TimeValue(action.RequestTime).After(aws.TimeValue(latest.RequestTime)) {
+			latest = action
+		}
+	}
+	return latest
+}
+
+func (c *cloud) CreateBackup(ctx context.Context, backupName string, backupOptions *BackupOptions) (*Backup, error) {
+	if len(backupOptions.FileSystemId) == 0 {
+		return nil, fmt.Errorf("FileSystemId is required")
+	}
+
+	input := &fsx.CreateBackupInput{
+		ClientRequestToken: aws.String(backupName),
+		FileSystemId:       aws.String(backupOptions.FileSystemId),
+		Tags: append([]*fsx.Tag{
+			{
+				Key:   aws.String(SnapshotNameTagKey),
+				Value: aws.String(backupName),
+			},
+		}, mapToTags(backupOptions.Tags)...),
+	}
+
+	output, err := c.fsx.CreateBackupWithContext(ctx, input)
+	if err != nil {
+		if isIncompatibleParameter(err) {
+			return nil, ErrBackupExistsDiffFs
+		}
+		if isFileSystemNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("CreateBackup failed: %v", err)
+	}
+
+	return newBackup(output.Backup), nil
+}
+
+func (c *cloud) DeleteBackup(ctx context.Context, backupId string) (err error) {
+	input := &fsx.DeleteBackupInput{
+		BackupId: aws.String(backupId),
+	}
+	if _, err = c.fsx.DeleteBackupWithContext(ctx, input); err != nil {
+		if isBackupNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("DeleteBackup failed: %v", err)
+	}
+	return nil
+}
+
+func (c *cloud) DescribeBackup(ctx context.Context, backupId string) (*Backup, error) {
+	backup, err := c.getBackup(ctx, backupId)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBackup(backup), nil
+}
+
+// DescribeBackups returns the backups taken by the driver of the given
+// filesystem, or of every filesystem if fileSystemId is empty. Other
+// user-initiated backups of the account are left out.
+func (c *cloud) DescribeBackups(ctx context.Context, fileSystemId string) ([]*Backup, error) {
+	input := &fsx.DescribeBackupsInput{
+		Filters: []*fsx.Filter{
+			{
+				Name:   aws.String(fsx.FilterNameBackupType),
+				Values: []*string{aws.String(fsx.BackupTypeUserInitiated)},
+			},
+		},
+	}
+	if fileSystemId != "" {
+		input.Filters = append(input.Filters, &fsx.Filter{
+			Name:   aws.String(fsx.FilterNameFileSystemId),
+			Values: []*string{aws.String(fileSystemId)},
+		})
+	}
+
+	var backups []*Backup
+	for {
+		output, err := c.fsx.DescribeBackupsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeBackups failed: %v", err)
+		}
+		for _, backup := range output.Backups {
+			if _, ok := tagsToMap(backup.Tags)[SnapshotNameTagKey]; !ok {
+				continue
+			}
+			backups = append(backups, newBackup(backup))
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	return backups, nil
+}
+
+func (c *cloud) getBackup(ctx context.Context, backupId string) (*fsx.Backup, error) {
+	input := &fsx.DescribeBackupsInput{
+		BackupIds: []*string{aws.String(backupId)},
+	}
+
+	output, err := c.fsx.DescribeBackupsWithContext(ctx, input)
+	if err != nil {
+		if isBackupNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if len(output.Backups) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return output.Backups[0], nil
+}
+
+func newBackup(backup *fsx.Backup) *Backup {
+	b := &Backup{
+		BackupId:     aws.StringValue(backup.BackupId),
+		CreationTime: aws.TimeValue(backup.CreationTime),
+		Lifecycle:    aws.StringValue(backup.Lifecycle),
+	}
+	if backup.FileSystem != nil {
+		b.FileSystemId = aws.StringValue(backup.FileSystem.FileSystemId)
+		b.CapacityGiB = aws.Int64Value(backup.FileSystem.StorageCapacity)
+		b.StorageType = aws.StringValue(backup.FileSystem.StorageType)
+		if backup.FileSystem.LustreConfiguration != nil {
+			b.DeploymentType = aws.StringValue(backup.FileSystem.LustreConfiguration.DeploymentType)
+		}
+	}
+	return b
+}
+
+// CreateExportTask starts a task exporting the changes of the filesystem to its
+// data repository. The task is created at most once per filesystem, later
+// calls return the task of the first one.
+func (c *cloud) CreateExportTask(ctx context.Context, fileSystemId string) (*DataRepositoryTask, error) {
+	input := &fsx.CreateDataRepositoryTaskInput{
+		ClientRequestToken: aws.String("export-" + fileSystemId),
+		FileSystemId:       aws.String(fileSystemId),
+		Type:               aws.String(fsx.DataRepositoryTaskTypeExportToRepository),
+		Report: &fsx.CompletionReport{
+			Enabled: aws.Bool(false),
+		},
+	}
+
+	output, err := c.fsx.CreateDataRepositoryTaskWithContext(ctx, input)
+	if err != nil {
+		if isFileSystemNotFound(err) {
+			return nil, ErrNotFound
+		}
+		if isDataRepositoryTaskExecuting(err) {
+			return nil, ErrDataRepositoryTaskRunning
+		}
+		return nil, fmt.Errorf("CreateDataRepositoryTask failed: %v", err)
+	}
+
+	return newDataRepositoryTask(output.DataRepositoryTask), nil
+}
+
+// WaitForDataRepositoryTask waits for a while until the data repository task
+// succeeds, and returns ErrDataRepositoryTaskRunning if it is still running.
+func (c *cloud) WaitForDataRepositoryTask(ctx context.Context, taskId string) error {
+	var (
+		checkInterval = 15 * time.Second
+		// exports of large filesystems take hours, so the caller is expected to
+		// retry rather than to block until the provisioner times out
+		checkTimeout = 1 * time.Minute
+	)
+	err := wait.PollImmediate(checkInterval, checkTimeout, func() (done bool, err error) {
+		input := &fsx.DescribeDataRepositoryTasksInput{
+			TaskIds: []*string{aws.String(taskId)},
+		}
+		output, err := c.fsx.DescribeDataRepositoryTasksWithContext(ctx, input)
+		if err != nil {
+			return true, err
+		}
+		if len(output.DataRepositoryTasks) == 0 {
+			return true, ErrNotFound
+		}
+		task := newDataRepositoryTask(output.DataRepositoryTasks[0])
+		klog.V(4).Infof("WaitForDataRepositoryTask task %s status is: %v", taskId, task.Lifecycle)
+		switch task.Lifecycle {
+		case fsx.DataRepositoryTaskLifecycleSucceeded:
+			return true, nil
+		case fsx.DataRepositoryTaskLifecyclePending, fsx.DataRepositoryTaskLifecycleExecuting:
+			return false, nil
+		default:
+			return true, fmt.Errorf("data repository task %s is %s: %s", taskId, task.Lifecycle, task.FailureDetails)
+		}
 	})
 
+	if err == wait.ErrWaitTimeout {
+		return ErrDataRepositoryTaskRunning
+	}
 	return err
+}
+
+func newDataRepositoryTask(task *fsx.DataRepositoryTask) *DataRepositoryTask {
+	t := &DataRepositoryTask{
+		TaskId:       aws.StringValue(task.TaskId),
+		FileSystemId: aws.StringValue(task.FileSystemId),
+		Lifecycle:    aws.StringValue(task.Lifecycle),
+	}
+	if task.FailureDetails != nil {
+		t.FailureDetails = aws.StringValue(task.FailureDetails.Message)
+	}
+	return t
+}
+
+// DescribeSubnets returns the subnets with the given IDs, in the same order.
+func (c *cloud) DescribeSubnets(ctx context.Context, subnetIds []string) ([]*Subnet, error) {
+	input := &ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIds),
+	}
+
+	output, err := c.ec2.DescribeSubnetsWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("DescribeSubnets failed: %v", err)
+	}
 
+	subnetsById := map[string]*Subnet{}
+	for _, subnet := range output.Subnets {
+		subnetsById[aws.StringValue(subnet.SubnetId)] = newSubnet(subnet)
+	}
+
+	var subnets []*Subnet
+	for _, subnetId := range subnetIds {
+		subnet, ok := subnetsById[subnetId]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// FindSubnets returns the subnets of the given VPC that carry all the given
+// tags, sorted by ID. A tag with an empty value matches any value.
+func (c *cloud) FindSubnets(ctx context.Context, vpcId string, tags map[string]string) ([]*Subnet, error) {
+	input := &ec2.DescribeSubnetsInput{
+		Filters: newFilters(vpcId, tags),
+	}
+
+	var subnets []*Subnet
+	for {
+		output, err := c.ec2.DescribeSubnetsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeSubnets failed: %v", err)
+		}
+		for _, subnet := range output.Subnets {
+			subnets = append(subnets, newSubnet(subnet))
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	if len(subnets) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.Slice(subnets, func(i, j int) bool {
+		return subnets[i].SubnetId < subnets[j].SubnetId
+	})
+	return subnets, nil
+}
+
+// FindSecurityGroups returns the IDs of the security groups of the given VPC
+// that carry all the given tags and, if any names are given, have one of
+// these names, sorted by ID.
+func (c *cloud) FindSecurityGroups(ctx context.Context, vpcId string, tags map[string]string, names []string) ([]string, error) {
+	filters := newFilters(vpcId, tags)
+	if len(names) > 0 {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("group-name"),
+			Values: aws.StringSlice(names),
+		})
+	}
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: filters,
+	}
+
+	var securityGroupIds []string
+	for {
+		output, err := c.ec2.DescribeSecurityGroupsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeSecurityGroups failed: %v", err)
+		}
+		for _, securityGroup := range output.SecurityGroups {
+			securityGroupIds = append(securityGroupIds, aws.StringValue(securityGroup.GroupId))
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	if len(securityGroupIds) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.Strings(securityGroupIds)
+	return securityGroupIds, nil
+}
+
+// GetInstanceVpcId returns the ID of the VPC the given instance runs in.
+func (c *cloud) GetInstanceVpcId(ctx context.Context, instanceId string) (string, error) {
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceId)},
+	}
+
+	output, err := c.ec2.DescribeInstancesWithContext(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("DescribeInstances failed: %v", err)
+	}
+
+	for _, reservation := range output.Reservations {
+		for _, instance := range reservation.Instances {
+			if aws.StringValue(instance.InstanceId) == instanceId {
+				return aws.StringValue(instance.VpcId), nil
+			}
+		}
+	}
+	return "", ErrNotFound
+}
+
+// GetAccountId returns the ID of the account of the credentials the cloud
+// makes requests with.
+func (c *cloud) GetAccountId(ctx context.Context) (string, error) {
+	output, err := c.sts.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("GetCallerIdentity failed: %v", err)
+	}
+	return aws.StringValue(output.Account), nil
+}
+
+func newFilters(vpcId string, tags map[string]string) []*ec2.Filter {
+	filters := []*ec2.Filter{
+		{
+			Name:   aws.String("vpc-id"),
+			Values: []*string{aws.String(vpcId)},
+		},
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if tags[key] == "" {
+			filters = append(filters, &ec2.Filter{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(key)},
+			})
+		} else {
+			filters = append(filters, &ec2.Filter{
+				Name:   aws.String("tag:" + key),
+				Values: []*string{aws.String(tags[key])},
+			})
+		}
+	}
+	return filters
+}
+
+func newSubnet(subnet *ec2.Subnet) *Subnet {
+	return &Subnet{
+		SubnetId:         aws.StringValue(subnet.SubnetId),
+		VpcId:            aws.StringValue(subnet.VpcId),
+		AvailabilityZone: aws.StringValue(subnet.AvailabilityZone),
+	}
 }
 
 func (c *cloud) getFileSystem(ctx context.Context, fileSystemId string) (*fsx.FileSystem, error) {
@@ -277,6 +1167,9 @@ func (c *cloud) getFileSystem(ctx context.Context, fileSystemId string) (*fsx.Fi
 
 	output, err := c.fsx.DescribeFileSystemsWithContext(ctx, input)
 	if err != nil {
+		if isFileSystemNotFound(err) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -300,6 +1193,15 @@ func isFileSystemNotFound(err error) bool {
 	return false
 }
 
+func isBackupNotFound(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		if awsErr.Code() == fsx.ErrCodeBackupNotFound {
+			return true
+		}
+	}
+	return false
+}
+
 func isIncompatibleParameter(err error) bool {
 	if awsErr, ok := err.(awserr.Error); ok {
 		if awsErr.Code() == fsx.ErrCodeIncompatibleParameterError {
@@ -308,3 +1210,12 @@ func isIncompatibleParameter(err error) bool {
 	}
 	return false
 }
+
+func isDataRepositoryTaskExecuting(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		if awsErr.Code() == fsx.ErrCodeDataRepositoryTaskExecuting {
+			return true
+		}
+	}
+	return false
+}
@@ -19,10 +19,16 @@ package cloud
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/fsx"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud/mocks"
 )
@@ -45,6 +51,7 @@ func TestCreateFileSystem(t *testing.T) {
 		DailyAutomaticBackupStartTime       = "00:00:00"
 		AutomaticBackupRetentionDays  int64 = 1
 		CopyTagsToBackups                   = true
+		backupId                            = "backup-0a1b2c3d4e5f6a7b8"
 	)
 	testCases := []struct {
 		name     string
@@ -586,6 +593,305 @@ func TestCreateFileSystem(t *testing.T) {
 					t.Fatalf("MountName mismatches. actual: %v expected: %v", resp.MountName, mountName)
 				}
 
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "success: tags",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &FileSystemOptions{
+					CapacityGiB:      volumeSizeGiB,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					ClusterId:        "cluster-1",
+					Tags: map[string]string{
+						"team":    "storage",
+						"cluster": "prod",
+					},
+				}
+
+				output := &fsx.CreateFileSystemOutput{
+					FileSystem: &fsx.FileSystem{
+						FileSystemId:    aws.String(fileSystemId),
+						StorageCapacity: aws.Int64(volumeSizeGiB),
+						DNSName:         aws.String(dnsname),
+						LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+							MountName: aws.String(mountName),
+						},
+					},
+				}
+				expTags := []*fsx.Tag{
+					{Key: aws.String(VolumeNameTagKey), Value: aws.String(volumeName)},
+					{Key: aws.String(ClusterIdTagKey), Value: aws.String("cluster-1")},
+					{Key: aws.String("cluster"), Value: aws.String("prod")},
+					{Key: aws.String("team"), Value: aws.String("storage")},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateFileSystemWithContext(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, input *fsx.CreateFileSystemInput, opts ...request.Option) (*fsx.CreateFileSystemOutput, error) {
+						if !reflect.DeepEqual(input.Tags, expTags) {
+							t.Fatalf("Tags mismatches. actual: %v expected: %v", input.Tags, expTags)
+						}
+						return output, nil
+					})
+				if _, err := c.CreateFileSystem(ctx, volumeName, req); err != nil {
+					t.Fatalf("CreateFileSystem is failed: %v", err)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "success: restore from backup",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &FileSystemOptions{
+					CapacityGiB:      volumeSizeGiB,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					DeploymentType:   fsx.LustreDeploymentTypePersistent1,
+					BackupId:         backupId,
+				}
+
+				describeOutput := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId: aws.String(backupId),
+							FileSystem: &fsx.FileSystem{
+								StorageCapacity: aws.Int64(2 * volumeSizeGiB),
+								StorageType:     aws.String(fsx.StorageTypeSsd),
+								LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+									DeploymentType: aws.String(fsx.LustreDeploymentTypePersistent1),
+								},
+							},
+						},
+					},
+				}
+				output := &fsx.CreateFileSystemFromBackupOutput{
+					FileSystem: &fsx.FileSystem{
+						FileSystemId:    aws.String(fileSystemId),
+						StorageCapacity: aws.Int64(2 * volumeSizeGiB),
+						DNSName:         aws.String(dnsname),
+						LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+							MountName: aws.String(mountName),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				mockFSx.EXPECT().CreateFileSystemFromBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				resp, err := c.CreateFileSystem(ctx, volumeName, req)
+				if err != nil {
+					t.Fatalf("CreateFileSystem is failed: %v", err)
+				}
+
+				if resp.FileSystemId != fileSystemId {
+					t.Fatalf("FileSystemId mismatches. actual: %v expected: %v", resp.FileSystemId, fileSystemId)
+				}
+
+				if resp.CapacityGiB != 2*volumeSizeGiB {
+					t.Fatalf("CapacityGiB mismatches. actual: %v expected: %v", resp.CapacityGiB, 2*volumeSizeGiB)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: backup smaller than requested capacity",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &FileSystemOptions{
+					CapacityGiB:      2 * volumeSizeGiB,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					BackupId:         backupId,
+				}
+
+				describeOutput := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId: aws.String(backupId),
+							FileSystem: &fsx.FileSystem{
+								StorageCapacity: aws.Int64(volumeSizeGiB),
+							},
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				_, err := c.CreateFileSystem(ctx, volumeName, req)
+				if err != ErrBackupTooSmall {
+					t.Fatalf("CreateFileSystem returned wrong error. actual: %v expected: %v", err, ErrBackupTooSmall)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "success: backup of the rounded up capacity beyond the capacity limit",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				// 2000GiB are requested with a limit of 2000GiB, and rounded
+				// up to 2400GiB like for a new SCRATCH_2 filesystem
+				req := &FileSystemOptions{
+					CapacityGiB:      2 * volumeSizeGiB,
+					CapacityLimitGiB: 2000,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					BackupId:         backupId,
+				}
+
+				describeOutput := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId: aws.String(backupId),
+							FileSystem: &fsx.FileSystem{
+								StorageCapacity: aws.Int64(2 * volumeSizeGiB),
+							},
+						},
+					},
+				}
+				output := &fsx.CreateFileSystemFromBackupOutput{
+					FileSystem: &fsx.FileSystem{
+						FileSystemId:    aws.String(fileSystemId),
+						StorageCapacity: aws.Int64(2 * volumeSizeGiB),
+						DNSName:         aws.String(dnsname),
+						LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+							MountName: aws.String(mountName),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				mockFSx.EXPECT().CreateFileSystemFromBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				if _, err := c.CreateFileSystem(ctx, volumeName, req); err != nil {
+					t.Fatalf("CreateFileSystem is failed: %v", err)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: backup larger than capacity limit",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &FileSystemOptions{
+					CapacityGiB:      volumeSizeGiB,
+					CapacityLimitGiB: volumeSizeGiB,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					BackupId:         backupId,
+				}
+
+				describeOutput := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId: aws.String(backupId),
+							FileSystem: &fsx.FileSystem{
+								StorageCapacity: aws.Int64(2 * volumeSizeGiB),
+							},
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				_, err := c.CreateFileSystem(ctx, volumeName, req)
+				if err != ErrBackupTooLarge {
+					t.Fatalf("CreateFileSystem returned wrong error. actual: %v expected: %v", err, ErrBackupTooLarge)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: backup of a different deployment type",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &FileSystemOptions{
+					CapacityGiB:      volumeSizeGiB,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					DeploymentType:   deploymentType,
+					BackupId:         backupId,
+				}
+
+				describeOutput := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId: aws.String(backupId),
+							FileSystem: &fsx.FileSystem{
+								StorageCapacity: aws.Int64(volumeSizeGiB),
+								LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+									DeploymentType: aws.String(fsx.LustreDeploymentTypePersistent1),
+								},
+							},
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				_, err := c.CreateFileSystem(ctx, volumeName, req)
+				if err != ErrBackupIncompatible {
+					t.Fatalf("CreateFileSystem returned wrong error. actual: %v expected: %v", err, ErrBackupIncompatible)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: backup not found",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &FileSystemOptions{
+					CapacityGiB:      volumeSizeGiB,
+					SubnetId:         subnetId,
+					SecurityGroupIds: securityGroupIds,
+					BackupId:         backupId,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, awserr.New(fsx.ErrCodeBackupNotFound, "", nil))
+				_, err := c.CreateFileSystem(ctx, volumeName, req)
+				if err != ErrNotFound {
+					t.Fatalf("CreateFileSystem returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+				}
+
 				mockCtl.Finish()
 			},
 		},
@@ -760,3 +1066,1323 @@ func TestDescribeFileSystem(t *testing.T) {
 		t.Run(tc.name, tc.testFunc)
 	}
 }
+
+func TestDescribeFileSystems(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: multiple pages",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				firstPage := &fsx.DescribeFileSystemsOutput{
+					FileSystems: []*fsx.FileSystem{
+						{
+							FileSystemId:        aws.String("fs-1"),
+							FileSystemType:      aws.String(fsx.FileSystemTypeLustre),
+							StorageCapacity:     aws.Int64(1200),
+							DNSName:             aws.String("fs-1.fsx.us-west-2.amazonaws.com"),
+							LustreConfiguration: &fsx.LustreFileSystemConfiguration{},
+						},
+						{
+							FileSystemId:   aws.String("fs-2"),
+							FileSystemType: aws.String(fsx.FileSystemTypeWindows),
+						},
+					},
+					NextToken: aws.String("token"),
+				}
+				secondPage := &fsx.DescribeFileSystemsOutput{
+					FileSystems: []*fsx.FileSystem{
+						{
+							FileSystemId:        aws.String("fs-3"),
+							FileSystemType:      aws.String(fsx.FileSystemTypeLustre),
+							StorageCapacity:     aws.Int64(2400),
+							DNSName:             aws.String("fs-3.fsx.us-west-2.amazonaws.com"),
+							LustreConfiguration: &fsx.LustreFileSystemConfiguration{},
+						},
+					},
+				}
+				ctx := context.Background()
+				gomock.InOrder(
+					mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(firstPage, nil),
+					mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(secondPage, nil),
+				)
+				resp, err := c.DescribeFileSystems(ctx)
+				if err != nil {
+					t.Fatalf("DescribeFileSystems is failed: %v", err)
+				}
+
+				if len(resp) != 2 {
+					t.Fatalf("Number of filesystems mismatches. actual: %v expected: %v", len(resp), 2)
+				}
+
+				if resp[1].FileSystemId != "fs-3" {
+					t.Fatalf("FileSystemId mismatches. actual: %v expected: %v", resp[1].FileSystemId, "fs-3")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "success: filesystem being created",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				output := &fsx.DescribeFileSystemsOutput{
+					FileSystems: []*fsx.FileSystem{
+						{
+							FileSystemId:   aws.String("fs-1"),
+							FileSystemType: aws.String(fsx.FileSystemTypeLustre),
+							Lifecycle:      aws.String(fsx.FileSystemLifecycleCreating),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				resp, err := c.DescribeFileSystems(ctx)
+				if err != nil {
+					t.Fatalf("DescribeFileSystems is failed: %v", err)
+				}
+
+				if len(resp) != 1 || resp[0].DnsName != "" || resp[0].CapacityGiB != 0 {
+					t.Fatalf("Filesystem mismatches. actual: %+v", resp)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: DescribeFileSystemsWithContext return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DescribeFileSystemsWithContext failed"))
+				_, err := c.DescribeFileSystems(ctx)
+				if err == nil {
+					t.Fatal("DescribeFileSystems is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestCreateBackup(t *testing.T) {
+	var (
+		backupName          = "snapshot-1234"
+		backupId            = "backup-1234"
+		fileSystemId        = "fs-1234"
+		volumeSizeGiB int64 = 1200
+		creationTime        = time.Now()
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &BackupOptions{
+					FileSystemId: fileSystemId,
+				}
+
+				output := &fsx.CreateBackupOutput{
+					Backup: &fsx.Backup{
+						BackupId:     aws.String(backupId),
+						CreationTime: aws.Time(creationTime),
+						Lifecycle:    aws.String(fsx.BackupLifecycleCreating),
+						FileSystem: &fsx.FileSystem{
+							FileSystemId:    aws.String(fileSystemId),
+							StorageCapacity: aws.Int64(volumeSizeGiB),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				resp, err := c.CreateBackup(ctx, backupName, req)
+				if err != nil {
+					t.Fatalf("CreateBackup is failed: %v", err)
+				}
+
+				if resp == nil {
+					t.Fatal("resp is nil")
+				}
+
+				if resp.BackupId != backupId {
+					t.Fatalf("BackupId mismatches. actual: %v expected: %v", resp.BackupId, backupId)
+				}
+
+				if resp.FileSystemId != fileSystemId {
+					t.Fatalf("FileSystemId mismatches. actual: %v expected: %v", resp.FileSystemId, fileSystemId)
+				}
+
+				if resp.CapacityGiB != volumeSizeGiB {
+					t.Fatalf("CapacityGiB mismatches. actual: %v expected: %v", resp.CapacityGiB, volumeSizeGiB)
+				}
+
+				if !resp.CreationTime.Equal(creationTime) {
+					t.Fatalf("CreationTime mismatches. actual: %v expected: %v", resp.CreationTime, creationTime)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: missing filesystem ID",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &BackupOptions{}
+
+				ctx := context.Background()
+				_, err := c.CreateBackup(ctx, backupName, req)
+				if err == nil {
+					t.Fatal("CreateBackup is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: backup exists with different filesystem",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &BackupOptions{
+					FileSystemId: fileSystemId,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, awserr.New(fsx.ErrCodeIncompatibleParameterError, "", nil))
+				_, err := c.CreateBackup(ctx, backupName, req)
+				if err != ErrBackupExistsDiffFs {
+					t.Fatalf("CreateBackup error mismatches. actual: %v expected: %v", err, ErrBackupExistsDiffFs)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: CreateBackupWithContext return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				req := &BackupOptions{
+					FileSystemId: fileSystemId,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("CreateBackupWithContext failed"))
+				_, err := c.CreateBackup(ctx, backupName, req)
+				if err == nil {
+					t.Fatal("CreateBackup is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestDeleteBackup(t *testing.T) {
+	var (
+		backupId = "backup-1234"
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				output := &fsx.DeleteBackupOutput{}
+				ctx := context.Background()
+				mockFSx.EXPECT().DeleteBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				err := c.DeleteBackup(ctx, backupId)
+				if err != nil {
+					t.Fatalf("DeleteBackup is failed: %v", err)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: backup not found",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().DeleteBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, awserr.New(fsx.ErrCodeBackupNotFound, "", nil))
+				err := c.DeleteBackup(ctx, backupId)
+				if err != ErrNotFound {
+					t.Fatalf("DeleteBackup error mismatches. actual: %v expected: %v", err, ErrNotFound)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: DeleteBackupWithContext return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().DeleteBackupWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DeleteBackupWithContext failed"))
+				err := c.DeleteBackup(ctx, backupId)
+				if err == nil {
+					t.Fatal("DeleteBackup is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestDescribeBackups(t *testing.T) {
+	var (
+		fileSystemId = "fs-1234"
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: multiple pages",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				firstPage := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId:   aws.String("backup-1"),
+							Lifecycle:  aws.String(fsx.BackupLifecycleAvailable),
+							FileSystem: &fsx.FileSystem{FileSystemId: aws.String(fileSystemId)},
+							Tags:       []*fsx.Tag{{Key: aws.String(SnapshotNameTagKey), Value: aws.String("snapshot-1")}},
+						},
+					},
+					NextToken: aws.String("token"),
+				}
+				secondPage := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId:   aws.String("backup-2"),
+							Lifecycle:  aws.String(fsx.BackupLifecycleCreating),
+							FileSystem: &fsx.FileSystem{FileSystemId: aws.String(fileSystemId)},
+							Tags:       []*fsx.Tag{{Key: aws.String(SnapshotNameTagKey), Value: aws.String("snapshot-2")}},
+						},
+					},
+				}
+				ctx := context.Background()
+				gomock.InOrder(
+					mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(firstPage, nil),
+					mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(secondPage, nil),
+				)
+				resp, err := c.DescribeBackups(ctx, fileSystemId)
+				if err != nil {
+					t.Fatalf("DescribeBackups is failed: %v", err)
+				}
+
+				if len(resp) != 2 {
+					t.Fatalf("Number of backups mismatches. actual: %v expected: %v", len(resp), 2)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "success: backups not taken by the driver are left out",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				output := &fsx.DescribeBackupsOutput{
+					Backups: []*fsx.Backup{
+						{
+							BackupId:   aws.String("backup-1"),
+							Lifecycle:  aws.String(fsx.BackupLifecycleAvailable),
+							FileSystem: &fsx.FileSystem{FileSystemId: aws.String(fileSystemId)},
+							Tags:       []*fsx.Tag{{Key: aws.String(SnapshotNameTagKey), Value: aws.String("snapshot-1")}},
+						},
+						{
+							BackupId:   aws.String("backup-2"),
+							Lifecycle:  aws.String(fsx.BackupLifecycleAvailable),
+							FileSystem: &fsx.FileSystem{FileSystemId: aws.String(fileSystemId)},
+							Tags:       []*fsx.Tag{{Key: aws.String("team"), Value: aws.String("storage")}},
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				resp, err := c.DescribeBackups(ctx, fileSystemId)
+				if err != nil {
+					t.Fatalf("DescribeBackups is failed: %v", err)
+				}
+
+				if len(resp) != 1 || resp[0].BackupId != "backup-1" {
+					t.Fatalf("Backups mismatch. actual: %+v expected: [backup-1]", resp)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: DescribeBackupsWithContext return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeBackupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DescribeBackupsWithContext failed"))
+				_, err := c.DescribeBackups(ctx, fileSystemId)
+				if err == nil {
+					t.Fatal("DescribeBackups is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestResizeFileSystem(t *testing.T) {
+	var (
+		fileSystemId        = "fs-1234"
+		volumeSizeGiB int64 = 1200
+		resizeGiB     int64 = 2400
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				describeOutput := &fsx.DescribeFileSystemsOutput{
+					FileSystems: []*fsx.FileSystem{
+						{
+							FileSystemId:    aws.String(fileSystemId),
+							StorageCapacity: aws.Int64(volumeSizeGiB),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				mockFSx.EXPECT().UpdateFileSystemWithContext(gomock.Eq(ctx), gomock.Any()).Return(&fsx.UpdateFileSystemOutput{}, nil)
+				newSizeGiB, err := c.ResizeFileSystem(ctx, fileSystemId, resizeGiB)
+				if err != nil {
+					t.Fatalf("ResizeFileSystem is failed: %v", err)
+				}
+
+				if newSizeGiB != resizeGiB {
+					t.Fatalf("newSizeGiB mismatches. actual: %v expected: %v", newSizeGiB, resizeGiB)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "success: update already in progress",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				describeOutput := &fsx.DescribeFileSystemsOutput{
+					FileSystems: []*fsx.FileSystem{
+						{
+							FileSystemId:    aws.String(fileSystemId),
+							StorageCapacity: aws.Int64(volumeSizeGiB),
+							AdministrativeActions: []*fsx.AdministrativeAction{
+								{
+									AdministrativeActionType: aws.String("FILE_SYSTEM_UPDATE"),
+									Status:                   aws.String("IN_PROGRESS"),
+									TargetFileSystemValues: &fsx.FileSystem{
+										StorageCapacity: aws.Int64(resizeGiB),
+									},
+								},
+							},
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				newSizeGiB, err := c.ResizeFileSystem(ctx, fileSystemId, resizeGiB)
+				if err != nil {
+					t.Fatalf("ResizeFileSystem is failed: %v", err)
+				}
+
+				if newSizeGiB != resizeGiB {
+					t.Fatalf("newSizeGiB mismatches. actual: %v expected: %v", newSizeGiB, resizeGiB)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: UpdateFileSystemWithContext return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				describeOutput := &fsx.DescribeFileSystemsOutput{
+					FileSystems: []*fsx.FileSystem{
+						{
+							FileSystemId:    aws.String(fileSystemId),
+							StorageCapacity: aws.Int64(volumeSizeGiB),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+				mockFSx.EXPECT().UpdateFileSystemWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("UpdateFileSystemWithContext failed"))
+				_, err := c.ResizeFileSystem(ctx, fileSystemId, resizeGiB)
+				if err == nil {
+					t.Fatal("ResizeFileSystem is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestWaitForFileSystemAvailable(t *testing.T) {
+	var (
+		fileSystemId = "fs-1234"
+	)
+	testCases := []struct {
+		name           string
+		lifecycle      string
+		deploymentType string
+		creationTime   time.Time
+		cancel         bool
+		expErr         bool
+	}{
+		{
+			name:           "success: filesystem available",
+			lifecycle:      "AVAILABLE",
+			deploymentType: fsx.LustreDeploymentTypeScratch2,
+			creationTime:   time.Now().Add(-10 * time.Minute),
+		},
+		{
+			name:           "fail: filesystem failed",
+			lifecycle:      "FAILED",
+			deploymentType: fsx.LustreDeploymentTypeScratch2,
+			creationTime:   time.Now().Add(-10 * time.Minute),
+			expErr:         true,
+		},
+		{
+			name:           "fail: filesystem creating longer than its deployment type's timeout",
+			lifecycle:      "CREATING",
+			deploymentType: fsx.LustreDeploymentTypeScratch2,
+			creationTime:   time.Now().Add(-20 * time.Minute),
+			expErr:         true,
+		},
+		{
+			name:           "fail: filesystem creating longer than the default timeout",
+			lifecycle:      "CREATING",
+			deploymentType: fsx.LustreDeploymentTypeScratch1,
+			creationTime:   time.Now().Add(-40 * time.Minute),
+			expErr:         true,
+		},
+		{
+			name:           "fail: context done while filesystem creating",
+			lifecycle:      "CREATING",
+			deploymentType: fsx.LustreDeploymentTypePersistent1,
+			creationTime:   time.Now().Add(-40 * time.Minute),
+			cancel:         true,
+			expErr:         true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			mockFSx := mocks.NewMockFSx(mockCtl)
+			c := &cloud{
+				fsx:          mockFSx,
+				pollInterval: DefaultPollInterval,
+				createTimeouts: map[string]time.Duration{
+					"":                                  30 * time.Minute,
+					fsx.LustreDeploymentTypeScratch2:    15 * time.Minute,
+					fsx.LustreDeploymentTypePersistent1: time.Hour,
+				},
+			}
+
+			describeOutput := &fsx.DescribeFileSystemsOutput{
+				FileSystems: []*fsx.FileSystem{
+					{
+						FileSystemId: aws.String(fileSystemId),
+						Lifecycle:    aws.String(tc.lifecycle),
+						CreationTime: aws.Time(tc.creationTime),
+						LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+							DeploymentType: aws.String(tc.deploymentType),
+						},
+					},
+				},
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.cancel {
+				cancel()
+			} else {
+				defer cancel()
+			}
+			mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+			err := c.WaitForFileSystemAvailable(ctx, fileSystemId)
+			if tc.expErr && err == nil {
+				t.Fatal("WaitForFileSystemAvailable is not failed")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatalf("WaitForFileSystemAvailable is failed: %v", err)
+			}
+
+			mockCtl.Finish()
+		})
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	option := WithRateLimit(10, 20)
+	var first, second CloudOptions
+	option(&first)
+	option(&second)
+	if first.limiter == nil || first.limiter != second.limiter {
+		t.Fatalf("Limiter is not shared. first: %p second: %p", first.limiter, second.limiter)
+	}
+
+	var unlimited CloudOptions
+	WithRateLimit(0, 0)(&unlimited)
+	if !unlimited.limiter.Allow() {
+		t.Fatal("Limiter with a rate of 0 does not allow requests")
+	}
+}
+
+func TestParseCreateTimeouts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected map[string]time.Duration
+		expErr   bool
+	}{
+		{
+			name:     "success: empty",
+			value:    "",
+			expected: map[string]time.Duration{},
+		},
+		{
+			name:  "success: default only",
+			value: "45m",
+			expected: map[string]time.Duration{
+				"": 45 * time.Minute,
+			},
+		},
+		{
+			name:  "success: default and deployment types",
+			value: "30m, PERSISTENT_1=1h,SCRATCH_2=15m",
+			expected: map[string]time.Duration{
+				"":                                  30 * time.Minute,
+				fsx.LustreDeploymentTypePersistent1: time.Hour,
+				fsx.LustreDeploymentTypeScratch2:    15 * time.Minute,
+			},
+		},
+		{
+			name:   "fail: invalid duration",
+			value:  "PERSISTENT_1=1 hour",
+			expErr: true,
+		},
+		{
+			name:   "fail: empty deployment type",
+			value:  "=1h",
+			expErr: true,
+		},
+		{
+			name:   "fail: duplicated deployment type",
+			value:  "PERSISTENT_1=1h,PERSISTENT_1=2h",
+			expErr: true,
+		},
+		{
+			name:   "fail: negative duration",
+			value:  "-1h",
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			createTimeouts, err := ParseCreateTimeouts(tc.value)
+			if tc.expErr {
+				if err == nil {
+					t.Fatal("ParseCreateTimeouts is not failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCreateTimeouts is failed: %v", err)
+			}
+			if !reflect.DeepEqual(createTimeouts, tc.expected) {
+				t.Fatalf("Create timeouts mismatch. actual: %v expected: %v", createTimeouts, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWaitForFileSystemResize(t *testing.T) {
+	var (
+		fileSystemId       = "fs-1234"
+		resizeGiB    int64 = 2400
+	)
+	testCases := []struct {
+		name   string
+		status string
+		expErr bool
+	}{
+		{
+			name:   "success: update completed",
+			status: "COMPLETED",
+		},
+		{
+			name:   "fail: update optimizing storage until canceled",
+			status: "UPDATED_OPTIMIZING",
+			expErr: true,
+		},
+		{
+			name:   "fail: update failed",
+			status: "FAILED",
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			mockFSx := mocks.NewMockFSx(mockCtl)
+			c := &cloud{
+				fsx: mockFSx,
+			}
+
+			describeOutput := &fsx.DescribeFileSystemsOutput{
+				FileSystems: []*fsx.FileSystem{
+					{
+						FileSystemId:    aws.String(fileSystemId),
+						StorageCapacity: aws.Int64(resizeGiB),
+						AdministrativeActions: []*fsx.AdministrativeAction{
+							{
+								AdministrativeActionType: aws.String("FILE_SYSTEM_UPDATE"),
+								Status:                   aws.String(tc.status),
+								TargetFileSystemValues: &fsx.FileSystem{
+									StorageCapacity: aws.Int64(resizeGiB),
+								},
+							},
+						},
+					},
+				},
+			}
+			// The context is canceled so that an update in progress fails
+			// after the first check instead of being polled again
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			mockFSx.EXPECT().DescribeFileSystemsWithContext(gomock.Eq(ctx), gomock.Any()).Return(describeOutput, nil)
+			err := c.WaitForFileSystemResize(ctx, fileSystemId, resizeGiB)
+			if tc.expErr && err == nil {
+				t.Fatal("WaitForFileSystemResize is not failed")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatalf("WaitForFileSystemResize is failed: %v", err)
+			}
+
+			mockCtl.Finish()
+		})
+	}
+}
+
+func TestCreateExportTask(t *testing.T) {
+	var (
+		fileSystemId = "fs-1234"
+		taskId       = "task-1234"
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				output := &fsx.CreateDataRepositoryTaskOutput{
+					DataRepositoryTask: &fsx.DataRepositoryTask{
+						TaskId:       aws.String(taskId),
+						FileSystemId: aws.String(fileSystemId),
+						Lifecycle:    aws.String(fsx.DataRepositoryTaskLifecyclePending),
+					},
+				}
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateDataRepositoryTaskWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				task, err := c.CreateExportTask(ctx, fileSystemId)
+				if err != nil {
+					t.Fatalf("CreateExportTask is failed: %v", err)
+				}
+
+				if task.TaskId != taskId {
+					t.Fatalf("TaskId mismatches. actual: %v expected: %v", task.TaskId, taskId)
+				}
+
+				if task.FileSystemId != fileSystemId {
+					t.Fatalf("FileSystemId mismatches. actual: %v expected: %v", task.FileSystemId, fileSystemId)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: another task is executing",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateDataRepositoryTaskWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, awserr.New(fsx.ErrCodeDataRepositoryTaskExecuting, "", nil))
+				_, err := c.CreateExportTask(ctx, fileSystemId)
+				if err != ErrDataRepositoryTaskRunning {
+					t.Fatalf("Expected error %v, got %v", ErrDataRepositoryTaskRunning, err)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: filesystem not found",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockFSx := mocks.NewMockFSx(mockCtl)
+				c := &cloud{
+					fsx: mockFSx,
+				}
+
+				ctx := context.Background()
+				mockFSx.EXPECT().CreateDataRepositoryTaskWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, awserr.New(fsx.ErrCodeFileSystemNotFound, "", nil))
+				_, err := c.CreateExportTask(ctx, fileSystemId)
+				if err != ErrNotFound {
+					t.Fatalf("Expected error %v, got %v", ErrNotFound, err)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestWaitForDataRepositoryTask(t *testing.T) {
+	var (
+		taskId = "task-1234"
+	)
+	testCases := []struct {
+		name      string
+		lifecycle string
+		expErr    bool
+	}{
+		{
+			name:      "success: task succeeded",
+			lifecycle: fsx.DataRepositoryTaskLifecycleSucceeded,
+		},
+		{
+			name:      "fail: task failed",
+			lifecycle: fsx.DataRepositoryTaskLifecycleFailed,
+			expErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			mockFSx := mocks.NewMockFSx(mockCtl)
+			c := &cloud{
+				fsx: mockFSx,
+			}
+
+			output := &fsx.DescribeDataRepositoryTasksOutput{
+				DataRepositoryTasks: []*fsx.DataRepositoryTask{
+					{
+						TaskId:    aws.String(taskId),
+						Lifecycle: aws.String(tc.lifecycle),
+					},
+				},
+			}
+			ctx := context.Background()
+			mockFSx.EXPECT().DescribeDataRepositoryTasksWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+			err := c.WaitForDataRepositoryTask(ctx, taskId)
+			if tc.expErr && err == nil {
+				t.Fatal("WaitForDataRepositoryTask is not failed")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatalf("WaitForDataRepositoryTask is failed: %v", err)
+			}
+
+			mockCtl.Finish()
+		})
+	}
+}
+
+func TestDescribeSubnets(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				output := &ec2.DescribeSubnetsOutput{
+					Subnets: []*ec2.Subnet{
+						{
+							SubnetId:         aws.String("subnet-2"),
+							VpcId:            aws.String("vpc-1"),
+							AvailabilityZone: aws.String("us-west-2b"),
+						},
+						{
+							SubnetId:         aws.String("subnet-1"),
+							VpcId:            aws.String("vpc-1"),
+							AvailabilityZone: aws.String("us-west-2a"),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				subnets, err := c.DescribeSubnets(ctx, []string{"subnet-1", "subnet-2"})
+				if err != nil {
+					t.Fatalf("DescribeSubnets is failed: %v", err)
+				}
+
+				if len(subnets) != 2 {
+					t.Fatalf("Number of subnets mismatches. actual: %v expected: %v", len(subnets), 2)
+				}
+
+				if subnets[0].SubnetId != "subnet-1" || subnets[0].AvailabilityZone != "us-west-2a" {
+					t.Fatalf("Subnet mismatches. actual: %+v expected: %v in %v", subnets[0], "subnet-1", "us-west-2a")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: subnet not found",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				output := &ec2.DescribeSubnetsOutput{
+					Subnets: []*ec2.Subnet{
+						{
+							SubnetId:         aws.String("subnet-1"),
+							AvailabilityZone: aws.String("us-west-2a"),
+						},
+					},
+				}
+				ctx := context.Background()
+				mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				_, err := c.DescribeSubnets(ctx, []string{"subnet-1", "subnet-2"})
+				if err != ErrNotFound {
+					t.Fatalf("DescribeSubnets returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestFindSubnets(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: paginated",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				ctx := context.Background()
+				firstPage := &ec2.DescribeSubnetsOutput{
+					Subnets: []*ec2.Subnet{
+						{
+							SubnetId:         aws.String("subnet-2"),
+							VpcId:            aws.String("vpc-1"),
+							AvailabilityZone: aws.String("us-west-2b"),
+						},
+					},
+					NextToken: aws.String("token"),
+				}
+				secondPage := &ec2.DescribeSubnetsOutput{
+					Subnets: []*ec2.Subnet{
+						{
+							SubnetId:         aws.String("subnet-1"),
+							VpcId:            aws.String("vpc-1"),
+							AvailabilityZone: aws.String("us-west-2a"),
+						},
+					},
+				}
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+						func(ctx context.Context, input *ec2.DescribeSubnetsInput, opts ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+							if len(input.Filters) != 2 {
+								t.Fatalf("Number of filters mismatches. actual: %v expected: %v", len(input.Filters), 2)
+							}
+							if aws.StringValue(input.Filters[0].Name) != "vpc-id" || aws.StringValue(input.Filters[0].Values[0]) != "vpc-1" {
+								t.Fatalf("VPC filter mismatches. actual: %v", input.Filters[0])
+							}
+							if aws.StringValue(input.Filters[1].Name) != "tag:fsx" || aws.StringValue(input.Filters[1].Values[0]) != "lustre" {
+								t.Fatalf("Tag filter mismatches. actual: %v", input.Filters[1])
+							}
+							return firstPage, nil
+						}),
+					mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Eq(ctx), gomock.Any()).Return(secondPage, nil),
+				)
+				subnets, err := c.FindSubnets(ctx, "vpc-1", map[string]string{"fsx": "lustre"})
+				if err != nil {
+					t.Fatalf("FindSubnets is failed: %v", err)
+				}
+
+				if len(subnets) != 2 {
+					t.Fatalf("Number of subnets mismatches. actual: %v expected: %v", len(subnets), 2)
+				}
+
+				if subnets[0].SubnetId != "subnet-1" || subnets[1].SubnetId != "subnet-2" {
+					t.Fatalf("Subnets are not sorted by ID. actual: %v, %v", subnets[0].SubnetId, subnets[1].SubnetId)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: no subnet matches",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				ctx := context.Background()
+				mockEC2.EXPECT().DescribeSubnetsWithContext(gomock.Eq(ctx), gomock.Any()).Return(&ec2.DescribeSubnetsOutput{}, nil)
+				_, err := c.FindSubnets(ctx, "vpc-1", map[string]string{"fsx": ""})
+				if err != ErrNotFound {
+					t.Fatalf("FindSubnets returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestFindSecurityGroups(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				ctx := context.Background()
+				output := &ec2.DescribeSecurityGroupsOutput{
+					SecurityGroups: []*ec2.SecurityGroup{
+						{GroupId: aws.String("sg-2")},
+						{GroupId: aws.String("sg-1")},
+					},
+				}
+				mockEC2.EXPECT().DescribeSecurityGroupsWithContext(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, opts ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+						if len(input.Filters) != 2 {
+							t.Fatalf("Number of filters mismatches. actual: %v expected: %v", len(input.Filters), 2)
+						}
+						if aws.StringValue(input.Filters[1].Name) != "group-name" || aws.StringValue(input.Filters[1].Values[0]) != "default" {
+							t.Fatalf("Group name filter mismatches. actual: %v", input.Filters[1])
+						}
+						return output, nil
+					})
+				securityGroupIds, err := c.FindSecurityGroups(ctx, "vpc-1", nil, []string{"default"})
+				if err != nil {
+					t.Fatalf("FindSecurityGroups is failed: %v", err)
+				}
+
+				if len(securityGroupIds) != 2 || securityGroupIds[0] != "sg-1" || securityGroupIds[1] != "sg-2" {
+					t.Fatalf("Security groups mismatch. actual: %v expected: %v", securityGroupIds, []string{"sg-1", "sg-2"})
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: DescribeSecurityGroups return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				ctx := context.Background()
+				mockEC2.EXPECT().DescribeSecurityGroupsWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DescribeSecurityGroups failed"))
+				_, err := c.FindSecurityGroups(ctx, "vpc-1", map[string]string{"fsx": "lustre"}, nil)
+				if err == nil {
+					t.Fatalf("FindSecurityGroups is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestGetInstanceVpcId(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				ctx := context.Background()
+				output := &ec2.DescribeInstancesOutput{
+					Reservations: []*ec2.Reservation{
+						{
+							Instances: []*ec2.Instance{
+								{
+									InstanceId: aws.String("i-1234"),
+									VpcId:      aws.String("vpc-1"),
+								},
+							},
+						},
+					},
+				}
+				mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				vpcId, err := c.GetInstanceVpcId(ctx, "i-1234")
+				if err != nil {
+					t.Fatalf("GetInstanceVpcId is failed: %v", err)
+				}
+
+				if vpcId != "vpc-1" {
+					t.Fatalf("VpcId mismatches. actual: %v expected: %v", vpcId, "vpc-1")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: instance not found",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEC2 := mocks.NewMockEC2(mockCtl)
+				c := &cloud{
+					ec2: mockEC2,
+				}
+
+				ctx := context.Background()
+				mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Eq(ctx), gomock.Any()).Return(&ec2.DescribeInstancesOutput{}, nil)
+				_, err := c.GetInstanceVpcId(ctx, "i-1234")
+				if err != ErrNotFound {
+					t.Fatalf("GetInstanceVpcId returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestGetAccountId(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success: normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockSTS := mocks.NewMockSTS(mockCtl)
+				c := &cloud{
+					sts: mockSTS,
+				}
+
+				ctx := context.Background()
+				output := &sts.GetCallerIdentityOutput{
+					Account: aws.String("123456789012"),
+				}
+				mockSTS.EXPECT().GetCallerIdentityWithContext(gomock.Eq(ctx), gomock.Any()).Return(output, nil)
+				accountId, err := c.GetAccountId(ctx)
+				if err != nil {
+					t.Fatalf("GetAccountId is failed: %v", err)
+				}
+
+				if accountId != "123456789012" {
+					t.Fatalf("AccountId mismatches. actual: %v expected: %v", accountId, "123456789012")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "fail: GetCallerIdentityWithContext return error",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockSTS := mocks.NewMockSTS(mockCtl)
+				c := &cloud{
+					sts: mockSTS,
+				}
+
+				ctx := context.Background()
+				mockSTS.EXPECT().GetCallerIdentityWithContext(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("GetCallerIdentityWithContext failed"))
+				if _, err := c.GetAccountId(ctx); err == nil {
+					t.Fatal("GetAccountId is not failed")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// FakeEC2 is an in-process fake of the EC2 API for testing. It serves the
+// instances, subnets and security groups it holds, and supports the vpc-id,
+// group-name, tag-key and tag:<key> filters.
+type FakeEC2 struct {
+	Instances      []*ec2.Instance
+	Subnets        []*ec2.Subnet
+	SecurityGroups []*ec2.SecurityGroup
+}
+
+var _ EC2 = &FakeEC2{}
+
+// NewFakeEC2 returns a FakeEC2 holding the given instance in vpc-1, which has
+// subnet-1 in the availability zone of the instance and security group sg-1.
+func NewFakeEC2(instanceId, availabilityZone string) *FakeEC2 {
+	return &FakeEC2{
+		Instances: []*ec2.Instance{
+			{
+				InstanceId: aws.String(instanceId),
+				VpcId:      aws.String("vpc-1"),
+				SubnetId:   aws.String("subnet-1"),
+			},
+		},
+		Subnets: []*ec2.Subnet{
+			{
+				SubnetId:         aws.String("subnet-1"),
+				VpcId:            aws.String("vpc-1"),
+				AvailabilityZone: aws.String(availabilityZone),
+			},
+		},
+		SecurityGroups: []*ec2.SecurityGroup{
+			{
+				GroupId:   aws.String("sg-1"),
+				GroupName: aws.String("default"),
+				VpcId:     aws.String("vpc-1"),
+			},
+		},
+	}
+}
+
+func (f *FakeEC2) DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	reservation := &ec2.Reservation{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		found := false
+		for _, instance := range f.Instances {
+			if aws.StringValue(instance.InstanceId) == id {
+				reservation.Instances = append(reservation.Instances, instance)
+				found = true
+			}
+		}
+		if !found {
+			return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", id), nil)
+		}
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{reservation},
+	}, nil
+}
+
+func (f *FakeEC2) DescribeSubnetsWithContext(ctx aws.Context, input *ec2.DescribeSubnetsInput, opts ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	for _, id := range aws.StringValueSlice(input.SubnetIds) {
+		found := false
+		for _, subnet := range f.Subnets {
+			if aws.StringValue(subnet.SubnetId) == id {
+				found = true
+			}
+		}
+		if !found {
+			return nil, awserr.New("InvalidSubnetID.NotFound", fmt.Sprintf("The subnet ID '%s' does not exist", id), nil)
+		}
+	}
+
+	output := &ec2.DescribeSubnetsOutput{}
+	for _, subnet := range f.Subnets {
+		if len(input.SubnetIds) > 0 && !containsString(aws.StringValueSlice(input.SubnetIds), aws.StringValue(subnet.SubnetId)) {
+			continue
+		}
+		if !matchEC2Filters(input.Filters, subnet.VpcId, nil, subnet.Tags) {
+			continue
+		}
+		output.Subnets = append(output.Subnets, subnet)
+	}
+	return output, nil
+}
+
+func (f *FakeEC2) DescribeSecurityGroupsWithContext(ctx aws.Context, input *ec2.DescribeSecurityGroupsInput, opts ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	output := &ec2.DescribeSecurityGroupsOutput{}
+	for _, securityGroup := range f.SecurityGroups {
+		if len(input.GroupIds) > 0 && !containsString(aws.StringValueSlice(input.GroupIds), aws.StringValue(securityGroup.GroupId)) {
+			continue
+		}
+		if !matchEC2Filters(input.Filters, securityGroup.VpcId, securityGroup.GroupName, securityGroup.Tags) {
+			continue
+		}
+		output.SecurityGroups = append(output.SecurityGroups, securityGroup)
+	}
+	return output, nil
+}
+
+// matchEC2Filters returns whether a resource with the given VPC ID, name and
+// tags matches all the filters.
+func matchEC2Filters(filters []*ec2.Filter, vpcId, name *string, tags []*ec2.Tag) bool {
+	for _, filter := range filters {
+		var values []string
+		switch key := aws.StringValue(filter.Name); {
+		case key == "vpc-id":
+			values = []string{aws.StringValue(vpcId)}
+		case key == "group-name":
+			values = []string{aws.StringValue(name)}
+		case key == "tag-key":
+			for _, tag := range tags {
+				values = append(values, aws.StringValue(tag.Key))
+			}
+		case strings.HasPrefix(key, "tag:"):
+			for _, tag := range tags {
+				if aws.StringValue(tag.Key) == strings.TrimPrefix(key, "tag:") {
+					values = append(values, aws.StringValue(tag.Value))
+				}
+			}
+		default:
+			return false
+		}
+
+		matched := false
+		for _, value := range values {
+			if containsString(aws.StringValueSlice(filter.Values), value) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,487 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/fsx"
+)
+
+// FakeFSx is an in-process fake of the FSx API for testing. Resources go
+// through the lifecycle of the real API, one step each time they are
+// described: filesystems and backups are CREATING when created and
+// AVAILABLE once described, deleted filesystems are DELETING until described
+// again, storage capacity updates are IN_PROGRESS, then UPDATED_OPTIMIZING,
+// then COMPLETED, and data repository tasks are PENDING, then SUCCEEDED.
+// Create requests are idempotent by ClientRequestToken, and fail with
+// IncompatibleParameterError if the token is reused with other parameters.
+type FakeFSx struct {
+	mux         sync.Mutex
+	fileSystems map[string]*fsx.FileSystem
+	backups     map[string]*fsx.Backup
+	tasks       map[string]*fsx.DataRepositoryTask
+	// requests maps the operation and client request token of create
+	// requests to their input and the ID of the resource they created
+	requests map[string]*fakeRequest
+}
+
+type fakeRequest struct {
+	input interface{}
+	id    string
+}
+
+var _ FSx = &FakeFSx{}
+
+func NewFakeFSx() *FakeFSx {
+	return &FakeFSx{
+		fileSystems: map[string]*fsx.FileSystem{},
+		backups:     map[string]*fsx.Backup{},
+		tasks:       map[string]*fsx.DataRepositoryTask{},
+		requests:    map[string]*fakeRequest{},
+	}
+}
+
+func (f *FakeFSx) CreateFileSystemWithContext(ctx aws.Context, input *fsx.CreateFileSystemInput, opts ...request.Option) (*fsx.CreateFileSystemOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if id, err := f.getRequest("CreateFileSystem", input.ClientRequestToken, input); err != nil || id != "" {
+		if err != nil {
+			return nil, err
+		}
+		return &fsx.CreateFileSystemOutput{FileSystem: f.copyFileSystem(id)}, nil
+	}
+
+	if aws.StringValue(input.FileSystemType) != fsx.FileSystemTypeLustre {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, "FileSystemType must be LUSTRE", nil)
+	}
+	if aws.Int64Value(input.StorageCapacity) <= 0 {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, "StorageCapacity must be positive", nil)
+	}
+	if len(input.SubnetIds) != 1 {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, "exactly one subnet is required", nil)
+	}
+
+	fs := f.newFileSystem(input.StorageCapacity, input.StorageType, input.SubnetIds, input.KmsKeyId, input.Tags, input.LustreConfiguration)
+	f.setRequest("CreateFileSystem", input.ClientRequestToken, input, aws.StringValue(fs.FileSystemId))
+	return &fsx.CreateFileSystemOutput{FileSystem: f.copyFileSystem(aws.StringValue(fs.FileSystemId))}, nil
+}
+
+func (f *FakeFSx) CreateFileSystemFromBackupWithContext(ctx aws.Context, input *fsx.CreateFileSystemFromBackupInput, opts ...request.Option) (*fsx.CreateFileSystemFromBackupOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if id, err := f.getRequest("CreateFileSystemFromBackup", input.ClientRequestToken, input); err != nil || id != "" {
+		if err != nil {
+			return nil, err
+		}
+		return &fsx.CreateFileSystemFromBackupOutput{FileSystem: f.copyFileSystem(id)}, nil
+	}
+
+	backup, ok := f.backups[aws.StringValue(input.BackupId)]
+	if !ok {
+		return nil, awserr.New(fsx.ErrCodeBackupNotFound, fmt.Sprintf("backup %s not found", aws.StringValue(input.BackupId)), nil)
+	}
+	if aws.StringValue(backup.Lifecycle) != fsx.BackupLifecycleAvailable {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, fmt.Sprintf("backup %s is not available", aws.StringValue(input.BackupId)), nil)
+	}
+	if len(input.SubnetIds) != 1 {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, "exactly one subnet is required", nil)
+	}
+
+	// the deployment type, capacity and encryption key are those of the
+	// backed up filesystem
+	lustreConfiguration := &fsx.CreateFileSystemLustreConfiguration{}
+	if input.LustreConfiguration != nil {
+		awsutil.Copy(lustreConfiguration, input.LustreConfiguration)
+	}
+	lustreConfiguration.DeploymentType = backup.FileSystem.LustreConfiguration.DeploymentType
+	fs := f.newFileSystem(backup.FileSystem.StorageCapacity, backup.FileSystem.StorageType, input.SubnetIds, backup.KmsKeyId, input.Tags, lustreConfiguration)
+	f.setRequest("CreateFileSystemFromBackup", input.ClientRequestToken, input, aws.StringValue(fs.FileSystemId))
+	return &fsx.CreateFileSystemFromBackupOutput{FileSystem: f.copyFileSystem(aws.StringValue(fs.FileSystemId))}, nil
+}
+
+func (f *FakeFSx) newFileSystem(storageCapacity *int64, storageType *string, subnetIds []*string, kmsKeyId *string, tags []*fsx.Tag, config *fsx.CreateFileSystemLustreConfiguration) *fsx.FileSystem {
+	id := fmt.Sprintf("fs-%017x", random.Uint64())
+	if storageType == nil {
+		storageType = aws.String(fsx.StorageTypeSsd)
+	}
+	fs := &fsx.FileSystem{
+		FileSystemId:    aws.String(id),
+		FileSystemType:  aws.String(fsx.FileSystemTypeLustre),
+		CreationTime:    aws.Time(time.Now()),
+		DNSName:         aws.String(id + ".fsx.us-east-1.amazonaws.com"),
+		Lifecycle:       aws.String(fsx.FileSystemLifecycleCreating),
+		StorageCapacity: storageCapacity,
+		StorageType:     storageType,
+		SubnetIds:       subnetIds,
+		KmsKeyId:        kmsKeyId,
+		Tags:            tags,
+		LustreConfiguration: &fsx.LustreFileSystemConfiguration{
+			DeploymentType: aws.String(fsx.LustreDeploymentTypeScratch1),
+			MountName:      aws.String(fmt.Sprintf("%08x", random.Uint32())),
+		},
+	}
+	if config != nil {
+		lustre := fs.LustreConfiguration
+		if config.DeploymentType != nil {
+			lustre.DeploymentType = config.DeploymentType
+		}
+		lustre.PerUnitStorageThroughput = config.PerUnitStorageThroughput
+		lustre.DriveCacheType = config.DriveCacheType
+		lustre.AutomaticBackupRetentionDays = config.AutomaticBackupRetentionDays
+		lustre.DailyAutomaticBackupStartTime = config.DailyAutomaticBackupStartTime
+		lustre.CopyTagsToBackups = config.CopyTagsToBackups
+		if config.ImportPath != nil {
+			lustre.DataRepositoryConfiguration = &fsx.DataRepositoryConfiguration{
+				AutoImportPolicy: config.AutoImportPolicy,
+				ImportPath:       config.ImportPath,
+				ExportPath:       config.ExportPath,
+			}
+		}
+	}
+	// the filesystem must not share memory with the input of the request
+	fs = awsutil.CopyOf(fs).(*fsx.FileSystem)
+	f.fileSystems[id] = fs
+	return fs
+}
+
+func (f *FakeFSx) DeleteFileSystemWithContext(ctx aws.Context, input *fsx.DeleteFileSystemInput, opts ...request.Option) (*fsx.DeleteFileSystemOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	fs, ok := f.fileSystems[aws.StringValue(input.FileSystemId)]
+	if !ok {
+		return nil, awserr.New(fsx.ErrCodeFileSystemNotFound, fmt.Sprintf("filesystem %s not found", aws.StringValue(input.FileSystemId)), nil)
+	}
+	if aws.StringValue(fs.Lifecycle) == fsx.FileSystemLifecycleCreating {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, fmt.Sprintf("filesystem %s is being created", aws.StringValue(input.FileSystemId)), nil)
+	}
+	fs.Lifecycle = aws.String(fsx.FileSystemLifecycleDeleting)
+	return &fsx.DeleteFileSystemOutput{
+		FileSystemId: fs.FileSystemId,
+		Lifecycle:    fs.Lifecycle,
+	}, nil
+}
+
+func (f *FakeFSx) DescribeFileSystemsWithContext(ctx aws.Context, input *fsx.DescribeFileSystemsInput, opts ...request.Option) (*fsx.DescribeFileSystemsOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	ids := aws.StringValueSlice(input.FileSystemIds)
+	if len(ids) == 0 {
+		for id := range f.fileSystems {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+
+	output := &fsx.DescribeFileSystemsOutput{}
+	for _, id := range ids {
+		fs, ok := f.fileSystems[id]
+		if !ok {
+			return nil, awserr.New(fsx.ErrCodeFileSystemNotFound, fmt.Sprintf("filesystem %s not found", id), nil)
+		}
+		f.advanceFileSystem(fs)
+		if aws.StringValue(fs.Lifecycle) == "" {
+			delete(f.fileSystems, id)
+			if len(input.FileSystemIds) > 0 {
+				return nil, awserr.New(fsx.ErrCodeFileSystemNotFound, fmt.Sprintf("filesystem %s not found", id), nil)
+			}
+			continue
+		}
+		output.FileSystems = append(output.FileSystems, f.copyFileSystem(id))
+	}
+	return output, nil
+}
+
+// advanceFileSystem moves the filesystem and its updates one step through
+// their lifecycle. A deleted filesystem is left with an empty lifecycle.
+func (f *FakeFSx) advanceFileSystem(fs *fsx.FileSystem) {
+	switch aws.StringValue(fs.Lifecycle) {
+	case fsx.FileSystemLifecycleCreating:
+		fs.Lifecycle = aws.String(fsx.FileSystemLifecycleAvailable)
+	case fsx.FileSystemLifecycleDeleting:
+		fs.Lifecycle = nil
+	}
+	for _, action := range fs.AdministrativeActions {
+		switch aws.StringValue(action.Status) {
+		case fsx.StatusInProgress:
+			action.Status = aws.String(fsx.StatusUpdatedOptimizing)
+			fs.StorageCapacity = action.TargetFileSystemValues.StorageCapacity
+		case fsx.StatusUpdatedOptimizing:
+			action.Status = aws.String(fsx.StatusCompleted)
+		}
+	}
+}
+
+func (f *FakeFSx) copyFileSystem(id string) *fsx.FileSystem {
+	return awsutil.CopyOf(f.fileSystems[id]).(*fsx.FileSystem)
+}
+
+func (f *FakeFSx) UpdateFileSystemWithContext(ctx aws.Context, input *fsx.UpdateFileSystemInput, opts ...request.Option) (*fsx.UpdateFileSystemOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	id := aws.StringValue(input.FileSystemId)
+	fs, ok := f.fileSystems[id]
+	if !ok {
+		return nil, awserr.New(fsx.ErrCodeFileSystemNotFound, fmt.Sprintf("filesystem %s not found", id), nil)
+	}
+	if aws.StringValue(fs.Lifecycle) != fsx.FileSystemLifecycleAvailable {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, fmt.Sprintf("filesystem %s is not available", id), nil)
+	}
+	if input.StorageCapacity != nil {
+		if aws.Int64Value(input.StorageCapacity) <= aws.Int64Value(fs.StorageCapacity) {
+			return nil, awserr.New(fsx.ErrCodeBadRequest, "StorageCapacity must be larger than the current storage capacity", nil)
+		}
+		for _, action := range fs.AdministrativeActions {
+			if aws.StringValue(action.Status) == fsx.StatusInProgress {
+				return nil, awserr.New(fsx.ErrCodeBadRequest, fmt.Sprintf("filesystem %s is being updated", id), nil)
+			}
+		}
+		fs.AdministrativeActions = append(fs.AdministrativeActions, &fsx.AdministrativeAction{
+			AdministrativeActionType: aws.String(fsx.AdministrativeActionTypeFileSystemUpdate),
+			RequestTime:              aws.Time(time.Now()),
+			Status:                   aws.String(fsx.StatusInProgress),
+			TargetFileSystemValues: &fsx.FileSystem{
+				StorageCapacity: input.StorageCapacity,
+			},
+		})
+	}
+	return &fsx.UpdateFileSystemOutput{FileSystem: f.copyFileSystem(id)}, nil
+}
+
+func (f *FakeFSx) CreateBackupWithContext(ctx aws.Context, input *fsx.CreateBackupInput, opts ...request.Option) (*fsx.CreateBackupOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if id, err := f.getRequest("CreateBackup", input.ClientRequestToken, input); err != nil || id != "" {
+		if err != nil {
+			return nil, err
+		}
+		return &fsx.CreateBackupOutput{Backup: f.copyBackup(id)}, nil
+	}
+
+	fs, ok := f.fileSystems[aws.StringValue(input.FileSystemId)]
+	if !ok {
+		return nil, awserr.New(fsx.ErrCodeFileSystemNotFound, fmt.Sprintf("filesystem %s not found", aws.StringValue(input.FileSystemId)), nil)
+	}
+	if aws.StringValue(fs.Lifecycle) != fsx.FileSystemLifecycleAvailable {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, fmt.Sprintf("filesystem %s is not available", aws.StringValue(input.FileSystemId)), nil)
+	}
+
+	id := fmt.Sprintf("backup-%017x", random.Uint64())
+	f.backups[id] = &fsx.Backup{
+		BackupId:     aws.String(id),
+		CreationTime: aws.Time(time.Now()),
+		FileSystem:   f.copyFileSystem(aws.StringValue(fs.FileSystemId)),
+		KmsKeyId:     fs.KmsKeyId,
+		Lifecycle:    aws.String(fsx.BackupLifecycleCreating),
+		Tags:         input.Tags,
+		Type:         aws.String(fsx.BackupTypeUserInitiated),
+	}
+	f.setRequest("CreateBackup", input.ClientRequestToken, input, id)
+	return &fsx.CreateBackupOutput{Backup: f.copyBackup(id)}, nil
+}
+
+func (f *FakeFSx) DeleteBackupWithContext(ctx aws.Context, input *fsx.DeleteBackupInput, opts ...request.Option) (*fsx.DeleteBackupOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	id := aws.StringValue(input.BackupId)
+	if _, ok := f.backups[id]; !ok {
+		return nil, awserr.New(fsx.ErrCodeBackupNotFound, fmt.Sprintf("backup %s not found", id), nil)
+	}
+	delete(f.backups, id)
+	return &fsx.DeleteBackupOutput{
+		BackupId:  aws.String(id),
+		Lifecycle: aws.String(fsx.BackupLifecycleDeleted),
+	}, nil
+}
+
+func (f *FakeFSx) DescribeBackupsWithContext(ctx aws.Context, input *fsx.DescribeBackupsInput, opts ...request.Option) (*fsx.DescribeBackupsOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	ids := aws.StringValueSlice(input.BackupIds)
+	if len(ids) == 0 {
+		for id := range f.backups {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+
+	output := &fsx.DescribeBackupsOutput{}
+	for _, id := range ids {
+		backup, ok := f.backups[id]
+		if !ok {
+			return nil, awserr.New(fsx.ErrCodeBackupNotFound, fmt.Sprintf("backup %s not found", id), nil)
+		}
+		if !matchBackupFilters(backup, input.Filters) {
+			continue
+		}
+		if aws.StringValue(backup.Lifecycle) == fsx.BackupLifecycleCreating {
+			backup.Lifecycle = aws.String(fsx.BackupLifecycleAvailable)
+		}
+		output.Backups = append(output.Backups, f.copyBackup(id))
+	}
+	return output, nil
+}
+
+func matchBackupFilters(backup *fsx.Backup, filters []*fsx.Filter) bool {
+	for _, filter := range filters {
+		var value string
+		switch aws.StringValue(filter.Name) {
+		case fsx.FilterNameFileSystemId:
+			value = aws.StringValue(backup.FileSystem.FileSystemId)
+		case fsx.FilterNameBackupType:
+			value = aws.StringValue(backup.Type)
+		default:
+			continue
+		}
+		matched := false
+		for _, v := range filter.Values {
+			if aws.StringValue(v) == value {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *FakeFSx) copyBackup(id string) *fsx.Backup {
+	return awsutil.CopyOf(f.backups[id]).(*fsx.Backup)
+}
+
+func (f *FakeFSx) CreateDataRepositoryTaskWithContext(ctx aws.Context, input *fsx.CreateDataRepositoryTaskInput, opts ...request.Option) (*fsx.CreateDataRepositoryTaskOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if id, err := f.getRequest("CreateDataRepositoryTask", input.ClientRequestToken, input); err != nil || id != "" {
+		if err != nil {
+			return nil, err
+		}
+		return &fsx.CreateDataRepositoryTaskOutput{DataRepositoryTask: f.copyTask(id)}, nil
+	}
+
+	fs, ok := f.fileSystems[aws.StringValue(input.FileSystemId)]
+	if !ok {
+		return nil, awserr.New(fsx.ErrCodeFileSystemNotFound, fmt.Sprintf("filesystem %s not found", aws.StringValue(input.FileSystemId)), nil)
+	}
+	if fs.LustreConfiguration.DataRepositoryConfiguration == nil {
+		return nil, awserr.New(fsx.ErrCodeBadRequest, fmt.Sprintf("filesystem %s is not linked to a data repository", aws.StringValue(input.FileSystemId)), nil)
+	}
+	for _, task := range f.tasks {
+		if aws.StringValue(task.FileSystemId) != aws.StringValue(input.FileSystemId) {
+			continue
+		}
+		switch aws.StringValue(task.Lifecycle) {
+		case fsx.DataRepositoryTaskLifecyclePending, fsx.DataRepositoryTaskLifecycleExecuting:
+			return nil, awserr.New(fsx.ErrCodeDataRepositoryTaskExecuting, fmt.Sprintf("task %s is executing", aws.StringValue(task.TaskId)), nil)
+		}
+	}
+
+	id := fmt.Sprintf("task-%017x", random.Uint64())
+	f.tasks[id] = &fsx.DataRepositoryTask{
+		TaskId:       aws.String(id),
+		CreationTime: aws.Time(time.Now()),
+		FileSystemId: input.FileSystemId,
+		Lifecycle:    aws.String(fsx.DataRepositoryTaskLifecyclePending),
+		Paths:        input.Paths,
+		Type:         input.Type,
+	}
+	f.setRequest("CreateDataRepositoryTask", input.ClientRequestToken, input, id)
+	return &fsx.CreateDataRepositoryTaskOutput{DataRepositoryTask: f.copyTask(id)}, nil
+}
+
+func (f *FakeFSx) DescribeDataRepositoryTasksWithContext(ctx aws.Context, input *fsx.DescribeDataRepositoryTasksInput, opts ...request.Option) (*fsx.DescribeDataRepositoryTasksOutput, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	ids := aws.StringValueSlice(input.TaskIds)
+	if len(ids) == 0 {
+		for id := range f.tasks {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+
+	output := &fsx.DescribeDataRepositoryTasksOutput{}
+	for _, id := range ids {
+		task, ok := f.tasks[id]
+		if !ok {
+			return nil, awserr.New(fsx.ErrCodeDataRepositoryTaskNotFound, fmt.Sprintf("task %s not found", id), nil)
+		}
+		switch aws.StringValue(task.Lifecycle) {
+		case fsx.DataRepositoryTaskLifecyclePending, fsx.DataRepositoryTaskLifecycleExecuting:
+			task.Lifecycle = aws.String(fsx.DataRepositoryTaskLifecycleSucceeded)
+			task.EndTime = aws.Time(time.Now())
+		}
+		output.DataRepositoryTasks = append(output.DataRepositoryTasks, f.copyTask(id))
+	}
+	return output, nil
+}
+
+func (f *FakeFSx) copyTask(id string) *fsx.DataRepositoryTask {
+	return awsutil.CopyOf(f.tasks[id]).(*fsx.DataRepositoryTask)
+}
+
+// getRequest returns the ID of the resource created by a previous request of
+// the operation with the same client request token, or an
+// IncompatibleParameterError if that request had a different input. Tokens
+// of deleted resources can be reused.
+func (f *FakeFSx) getRequest(operation string, token *string, input interface{}) (string, error) {
+	if token == nil {
+		return "", nil
+	}
+	req, ok := f.requests[operation+"/"+aws.StringValue(token)]
+	if !ok || (f.fileSystems[req.id] == nil && f.backups[req.id] == nil && f.tasks[req.id] == nil) {
+		return "", nil
+	}
+	// the token of a deleted filesystem can be reused, e.g. by a volume of
+	// the same name, as soon as it is being deleted
+	if fs := f.fileSystems[req.id]; fs != nil && aws.StringValue(fs.Lifecycle) == fsx.FileSystemLifecycleDeleting {
+		return "", nil
+	}
+	if !reflect.DeepEqual(req.input, input) {
+		return "", awserr.New(fsx.ErrCodeIncompatibleParameterError, fmt.Sprintf("client request token %s was used with different parameters", aws.StringValue(token)), nil)
+	}
+	return req.id, nil
+}
+
+func (f *FakeFSx) setRequest(operation string, token *string, input interface{}, id string) {
+	if token == nil {
+		return
+	}
+	f.requests[operation+"/"+aws.StringValue(token)] = &fakeRequest{
+		input: awsutil.CopyOf(input),
+		id:    id,
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/fsx"
+)
+
+func newFakeCloud() *cloud {
+	return NewFakeCloud(NewFakeFSx(), NewFakeEC2("i-1234", "us-east-1a")).(*cloud)
+}
+
+func TestFakeFSxFileSystemLifecycle(t *testing.T) {
+	var (
+		volumeName = "volumeName"
+		fsOptions  = &FileSystemOptions{
+			CapacityGiB:      1200,
+			SubnetId:         "subnet-1",
+			SecurityGroupIds: []string{"sg-1"},
+			DeploymentType:   "SCRATCH_2",
+		}
+	)
+	c := newFakeCloud()
+	ctx := context.Background()
+
+	fs, err := c.CreateFileSystem(ctx, volumeName, fsOptions)
+	if err != nil {
+		t.Fatalf("CreateFileSystem is failed: %v", err)
+	}
+	if fs.Lifecycle != "CREATING" {
+		t.Fatalf("Lifecycle mismatches. actual: %v expected: %v", fs.Lifecycle, "CREATING")
+	}
+	if fs.DeploymentType != "SCRATCH_2" {
+		t.Fatalf("DeploymentType mismatches. actual: %v expected: %v", fs.DeploymentType, "SCRATCH_2")
+	}
+	if fs.Tags[VolumeNameTagKey] != volumeName {
+		t.Fatalf("Volume name tag mismatches. actual: %v expected: %v", fs.Tags[VolumeNameTagKey], volumeName)
+	}
+
+	// the same request is idempotent
+	sameFs, err := c.CreateFileSystem(ctx, volumeName, fsOptions)
+	if err != nil {
+		t.Fatalf("CreateFileSystem is failed: %v", err)
+	}
+	if sameFs.FileSystemId != fs.FileSystemId {
+		t.Fatalf("FileSystemId mismatches. actual: %v expected: %v", sameFs.FileSystemId, fs.FileSystemId)
+	}
+
+	// a request of the same name with a different size is rejected
+	_, err = c.CreateFileSystem(ctx, volumeName, &FileSystemOptions{
+		CapacityGiB:      2400,
+		SubnetId:         "subnet-1",
+		SecurityGroupIds: []string{"sg-1"},
+		DeploymentType:   "SCRATCH_2",
+	})
+	if err != ErrFsExistsDiffSize {
+		t.Fatalf("CreateFileSystem returned wrong error. actual: %v expected: %v", err, ErrFsExistsDiffSize)
+	}
+
+	if err := c.WaitForFileSystemAvailable(ctx, fs.FileSystemId); err != nil {
+		t.Fatalf("WaitForFileSystemAvailable is failed: %v", err)
+	}
+
+	newSizeGiB, err := c.ResizeFileSystem(ctx, fs.FileSystemId, 2400)
+	if err != nil {
+		t.Fatalf("ResizeFileSystem is failed: %v", err)
+	}
+	if err := c.WaitForFileSystemResize(ctx, fs.FileSystemId, newSizeGiB); err != nil {
+		t.Fatalf("WaitForFileSystemResize is failed: %v", err)
+	}
+	fs, err = c.DescribeFileSystem(ctx, fs.FileSystemId)
+	if err != nil {
+		t.Fatalf("DescribeFileSystem is failed: %v", err)
+	}
+	if fs.CapacityGiB != 2400 {
+		t.Fatalf("CapacityGiB mismatches. actual: %v expected: %v", fs.CapacityGiB, 2400)
+	}
+
+	fileSystems, err := c.DescribeFileSystems(ctx)
+	if err != nil {
+		t.Fatalf("DescribeFileSystems is failed: %v", err)
+	}
+	if len(fileSystems) != 1 {
+		t.Fatalf("Number of filesystems mismatches. actual: %v expected: %v", len(fileSystems), 1)
+	}
+
+	if err := c.DeleteFileSystem(ctx, fs.FileSystemId); err != nil {
+		t.Fatalf("DeleteFileSystem is failed: %v", err)
+	}
+	// the name of a filesystem being deleted can be reused
+	newFs, err := c.CreateFileSystem(ctx, volumeName, fsOptions)
+	if err != nil {
+		t.Fatalf("CreateFileSystem is failed: %v", err)
+	}
+	if newFs.FileSystemId == fs.FileSystemId {
+		t.Fatalf("CreateFileSystem returned the deleted filesystem %v", fs.FileSystemId)
+	}
+	_, err = c.DescribeFileSystem(ctx, fs.FileSystemId)
+	if err != ErrNotFound {
+		t.Fatalf("DescribeFileSystem returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+	}
+	if err := c.DeleteFileSystem(ctx, "fs-1234"); err != ErrNotFound {
+		t.Fatalf("DeleteFileSystem returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+	}
+}
+
+func TestFakeFSxBackupLifecycle(t *testing.T) {
+	c := newFakeCloud()
+	ctx := context.Background()
+
+	fs, err := c.CreateFileSystem(ctx, "volumeName", &FileSystemOptions{
+		CapacityGiB:      2400,
+		SubnetId:         "subnet-1",
+		SecurityGroupIds: []string{"sg-1"},
+		DeploymentType:   "PERSISTENT_1",
+	})
+	if err != nil {
+		t.Fatalf("CreateFileSystem is failed: %v", err)
+	}
+
+	// filesystems can't be backed up while they are being created
+	_, err = c.CreateBackup(ctx, "snapshotName", &BackupOptions{FileSystemId: fs.FileSystemId})
+	if err == nil {
+		t.Fatal("CreateBackup is not failed")
+	}
+
+	if err := c.WaitForFileSystemAvailable(ctx, fs.FileSystemId); err != nil {
+		t.Fatalf("WaitForFileSystemAvailable is failed: %v", err)
+	}
+	backup, err := c.CreateBackup(ctx, "snapshotName", &BackupOptions{FileSystemId: fs.FileSystemId})
+	if err != nil {
+		t.Fatalf("CreateBackup is failed: %v", err)
+	}
+	if backup, err = c.DescribeBackup(ctx, backup.BackupId); err != nil || backup.Lifecycle != fsx.BackupLifecycleAvailable {
+		t.Fatalf("DescribeBackup returned wrong backup. backup: %+v err: %v", backup, err)
+	}
+	if _, err := c.CreateBackup(ctx, "snapshotName", &BackupOptions{FileSystemId: "fs-1234"}); err != ErrBackupExistsDiffFs {
+		t.Fatalf("CreateBackup returned wrong error. actual: %v expected: %v", err, ErrBackupExistsDiffFs)
+	}
+
+	backups, err := c.DescribeBackups(ctx, fs.FileSystemId)
+	if err != nil {
+		t.Fatalf("DescribeBackups is failed: %v", err)
+	}
+	if len(backups) != 1 || backups[0].BackupId != backup.BackupId {
+		t.Fatalf("Backups mismatch. actual: %v expected: %v", backups, backup)
+	}
+
+	_, err = c.CreateFileSystem(ctx, "restoredName", &FileSystemOptions{
+		CapacityGiB:      3600,
+		SubnetId:         "subnet-1",
+		SecurityGroupIds: []string{"sg-1"},
+		BackupId:         backup.BackupId,
+	})
+	if err != ErrBackupTooSmall {
+		t.Fatalf("CreateFileSystem returned wrong error. actual: %v expected: %v", err, ErrBackupTooSmall)
+	}
+	restored, err := c.CreateFileSystem(ctx, "restoredName", &FileSystemOptions{
+		CapacityGiB:      1200,
+		SubnetId:         "subnet-1",
+		SecurityGroupIds: []string{"sg-1"},
+		BackupId:         backup.BackupId,
+	})
+	if err != nil {
+		t.Fatalf("CreateFileSystem is failed: %v", err)
+	}
+	if restored.CapacityGiB != 2400 || restored.DeploymentType != "PERSISTENT_1" {
+		t.Fatalf("Restored filesystem mismatches the backup: %+v", restored)
+	}
+
+	if err := c.DeleteBackup(ctx, backup.BackupId); err != nil {
+		t.Fatalf("DeleteBackup is failed: %v", err)
+	}
+	if _, err := c.DescribeBackup(ctx, backup.BackupId); err != ErrNotFound {
+		t.Fatalf("DescribeBackup returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+	}
+	if err := c.DeleteBackup(ctx, backup.BackupId); err != ErrNotFound {
+		t.Fatalf("DeleteBackup returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+	}
+}
+
+func TestFakeFSxExportTask(t *testing.T) {
+	c := newFakeCloud()
+	ctx := context.Background()
+
+	fs, err := c.CreateFileSystem(ctx, "volumeName", &FileSystemOptions{
+		CapacityGiB:      1200,
+		SubnetId:         "subnet-1",
+		SecurityGroupIds: []string{"sg-1"},
+		S3ImportPath:     "s3://bucket",
+		ExportOnDelete:   true,
+	})
+	if err != nil {
+		t.Fatalf("CreateFileSystem is failed: %v", err)
+	}
+
+	task, err := c.CreateExportTask(ctx, fs.FileSystemId)
+	if err != nil {
+		t.Fatalf("CreateExportTask is failed: %v", err)
+	}
+	// the retry of a request returns the same task
+	sameTask, err := c.CreateExportTask(ctx, fs.FileSystemId)
+	if err != nil {
+		t.Fatalf("CreateExportTask is failed: %v", err)
+	}
+	if sameTask.TaskId != task.TaskId {
+		t.Fatalf("TaskId mismatches. actual: %v expected: %v", sameTask.TaskId, task.TaskId)
+	}
+	if err := c.WaitForDataRepositoryTask(ctx, task.TaskId); err != nil {
+		t.Fatalf("WaitForDataRepositoryTask is failed: %v", err)
+	}
+
+	if _, err := c.CreateExportTask(ctx, "fs-1234"); err != ErrNotFound {
+		t.Fatalf("CreateExportTask returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+	}
+}
+
+func TestFakeEC2Discovery(t *testing.T) {
+	c := newFakeCloud()
+	ctx := context.Background()
+
+	vpcId, err := c.GetInstanceVpcId(ctx, "i-1234")
+	if err != nil {
+		t.Fatalf("GetInstanceVpcId is failed: %v", err)
+	}
+	subnets, err := c.FindSubnets(ctx, vpcId, nil)
+	if err != nil {
+		t.Fatalf("FindSubnets is failed: %v", err)
+	}
+	if len(subnets) != 1 || subnets[0].SubnetId != "subnet-1" || subnets[0].AvailabilityZone != "us-east-1a" {
+		t.Fatalf("Subnets mismatch: %v", subnets)
+	}
+	if _, err := c.FindSubnets(ctx, vpcId, map[string]string{"fsx": ""}); err != ErrNotFound {
+		t.Fatalf("FindSubnets returned wrong error. actual: %v expected: %v", err, ErrNotFound)
+	}
+	securityGroupIds, err := c.FindSecurityGroups(ctx, vpcId, nil, []string{"default"})
+	if err != nil {
+		t.Fatalf("FindSecurityGroups is failed: %v", err)
+	}
+	if len(securityGroupIds) != 1 || securityGroupIds[0] != "sg-1" {
+		t.Fatalf("Security groups mismatch: %v", securityGroupIds)
+	}
+}
@@ -17,8 +17,6 @@ limitations under the License.
 package cloud
 
 import (
-	"context"
-	"fmt"
 	"math/rand"
 	"time"
 )
@@ -29,61 +27,17 @@ func init() {
 	random = rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
-type FakeCloudProvider struct {
-	m           *metadata
-	fileSystems map[string]*FileSystem
-}
-
-func NewFakeCloudProvider() *FakeCloudProvider {
-	return &FakeCloudProvider{
-		m:           &metadata{"instanceID", "region", "az"},
-		fileSystems: make(map[string]*FileSystem),
-	}
-}
-
-func (c *FakeCloudProvider) GetMetadata() MetadataService {
-	return c.m
-}
-
-func (c *FakeCloudProvider) CreateFileSystem(ctx context.Context, volumeName string, fileSystemOptions *FileSystemOptions) (fs *FileSystem, err error) {
-	fs, exists := c.fileSystems[volumeName]
-	if exists {
-		if fs.CapacityGiB == fileSystemOptions.CapacityGiB {
-			return fs, nil
-		} else {
-			return nil, ErrFsExistsDiffSize
-		}
-	}
-
-	fs = &FileSystem{
-		FileSystemId: fmt.Sprintf("fs-%d", random.Uint64()),
-		CapacityGiB:  fileSystemOptions.CapacityGiB,
-		DnsName:      "test.us-east-1.fsx.amazonaws.com",
-		MountName:    "random",
-	}
-	c.fileSystems[volumeName] = fs
-	return fs, nil
-}
-
-func (c *FakeCloudProvider) DeleteFileSystem(ctx context.Context, volumeID string) (err error) {
-	delete(c.fileSystems, volumeID)
-	for name, fs := range c.fileSystems {
-		if fs.FileSystemId == volumeID {
-			delete(c.fileSystems, name)
-		}
-	}
-	return nil
-}
-
-func (c *FakeCloudProvider) DescribeFileSystem(ctx context.Context, volumeID string) (fs *FileSystem, err error) {
-	for _, fs := range c.fileSystems {
-		if fs.FileSystemId == volumeID {
-			return fs, nil
-		}
+// NewFakeCloud returns a cloud backed by the given fakes of the FSx and EC2
+// APIs, so that the cloud can be exercised end to end without AWS.
+func NewFakeCloud(fsx FSx, ec2 EC2) Cloud {
+	return &cloud{
+		fsx:          fsx,
+		ec2:          ec2,
+		pollInterval: 100 * time.Millisecond,
 	}
-	return nil, ErrNotFound
 }
 
-func (c *FakeCloudProvider) WaitForFileSystemAvailable(ctx context.Context, fileSystemId string) error {
-	return nil
+// NewFakeMetadata returns the metadata of a fake instance
+func NewFakeMetadata() MetadataService {
+	return &metadata{"instanceID", "region", "az"}
 }
@@ -18,10 +18,14 @@ package cloud
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
 )
 
 type EC2Metadata interface {
@@ -59,11 +63,37 @@ func (m *metadata) GetAvailabilityZone() string {
 	return m.availabilityZone
 }
 
-// NewMetadataService returns a new MetadataServiceImplementation.
+// NewMetadata returns a MetadataService from the static environment variables
+// if AWS_INSTANCE_ID is set, otherwise from EC2 instance metadata, falling back
+// to the Kubernetes node named by CSI_NODE_NAME when instance metadata is not
+// available.
 func NewMetadata() (MetadataService, error) {
+	if len(os.Getenv(InstanceIDEnvVar)) > 0 {
+		return NewEnvMetadataService()
+	}
+
 	sess := session.Must(session.NewSession(&aws.Config{}))
 	svc := ec2metadata.New(sess)
-	return NewMetadataService(svc)
+	m, err := NewMetadataService(svc)
+	if err == nil {
+		return m, nil
+	}
+
+	nodeName := os.Getenv(NodeNameEnvVar)
+	if len(nodeName) == 0 {
+		return nil, err
+	}
+	klog.Warningf("Falling back to metadata of node %s: %v", nodeName, err)
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not get in-cluster config: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Kubernetes client: %v", err)
+	}
+	return NewKubernetesMetadataService(clientset, nodeName)
 }
 
 // NewMetadataService returns a new MetadataServiceImplementation.
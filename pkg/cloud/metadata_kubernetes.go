@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// NodeNameEnvVar is the environment variable holding the name of the
+	// Kubernetes node the driver runs on.
+	NodeNameEnvVar = "CSI_NODE_NAME"
+
+	// Environment variables of the static metadata
+	InstanceIDEnvVar       = "AWS_INSTANCE_ID"
+	RegionEnvVar           = "AWS_REGION"
+	AvailabilityZoneEnvVar = "AWS_AVAILABILITY_ZONE"
+)
+
+var (
+	// providerIDRegexp matches the provider ID of AWS nodes, e.g.
+	// aws:///us-west-2a/i-0123456789abcdef0
+	providerIDRegexp = regexp.MustCompile(`^aws:///([^/]*)/(i-[0-9a-f]+)$`)
+
+	regionLabels = []string{"topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"}
+	zoneLabels   = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"}
+)
+
+// NewKubernetesMetadataService returns a MetadataService that reads the
+// instance ID from the provider ID of the given node, and the region and
+// availability zone from its topology labels.
+func NewKubernetesMetadataService(clientset kubernetes.Interface, nodeName string) (MetadataService, error) {
+	node, err := clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get node %s: %v", nodeName, err)
+	}
+
+	match := providerIDRegexp.FindStringSubmatch(node.Spec.ProviderID)
+	if match == nil {
+		return nil, fmt.Errorf("could not get valid EC2 instance ID from provider ID %q of node %s", node.Spec.ProviderID, nodeName)
+	}
+
+	availabilityZone := getLabel(node.Labels, zoneLabels)
+	if len(availabilityZone) == 0 {
+		availabilityZone = match[1]
+	}
+	if len(availabilityZone) == 0 {
+		return nil, fmt.Errorf("could not get valid availability zone of node %s", nodeName)
+	}
+
+	region := getLabel(node.Labels, regionLabels)
+	if len(region) == 0 {
+		// the region is the availability zone without its letter suffix
+		region = strings.TrimRight(availabilityZone, "abcdefghijklmnopqrstuvwxyz")
+	}
+
+	return &metadata{
+		instanceID:       match[2],
+		region:           region,
+		availabilityZone: availabilityZone,
+	}, nil
+}
+
+// NewEnvMetadataService returns a MetadataService that reads the instance ID,
+// region and availability zone from environment variables. It is meant for
+// testing the driver outside of EC2.
+func NewEnvMetadataService() (MetadataService, error) {
+	m := &metadata{
+		instanceID:       os.Getenv(InstanceIDEnvVar),
+		region:           os.Getenv(RegionEnvVar),
+		availabilityZone: os.Getenv(AvailabilityZoneEnvVar),
+	}
+
+	if len(m.instanceID) == 0 {
+		return nil, fmt.Errorf("%s is not set", InstanceIDEnvVar)
+	}
+
+	if len(m.region) == 0 {
+		return nil, fmt.Errorf("%s is not set", RegionEnvVar)
+	}
+
+	if len(m.availabilityZone) == 0 {
+		return nil, fmt.Errorf("%s is not set", AvailabilityZoneEnvVar)
+	}
+
+	return m, nil
+}
+
+func getLabel(labels map[string]string, keys []string) string {
+	for _, key := range keys {
+		if val := labels[key]; len(val) > 0 {
+			return val
+		}
+	}
+	return ""
+}
@@ -18,11 +18,15 @@ package cloud
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud/mocks"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 var (
@@ -141,3 +145,122 @@ func TestNewMetadataService(t *testing.T) {
 		})
 	}
 }
+
+func TestNewKubernetesMetadataService(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		providerID               string
+		labels                   map[string]string
+		expectedInstanceID       string
+		expectedRegion           string
+		expectedAvailabilityZone string
+		expectErr                bool
+	}{
+		{
+			name:       "success: topology labels",
+			providerID: "aws:///us-west-2a/i-0123456789abcdef0",
+			labels: map[string]string{
+				"topology.kubernetes.io/region": "us-west-2",
+				"topology.kubernetes.io/zone":   "us-west-2b",
+			},
+			expectedInstanceID:       "i-0123456789abcdef0",
+			expectedRegion:           "us-west-2",
+			expectedAvailabilityZone: "us-west-2b",
+		},
+		{
+			name:       "success: beta topology labels",
+			providerID: "aws:///us-west-2a/i-0123456789abcdef0",
+			labels: map[string]string{
+				"failure-domain.beta.kubernetes.io/region": "us-west-2",
+				"failure-domain.beta.kubernetes.io/zone":   "us-west-2a",
+			},
+			expectedInstanceID:       "i-0123456789abcdef0",
+			expectedRegion:           "us-west-2",
+			expectedAvailabilityZone: "us-west-2a",
+		},
+		{
+			name:                     "success: zone from provider ID",
+			providerID:               "aws:///us-west-2c/i-0123456789abcdef0",
+			expectedInstanceID:       "i-0123456789abcdef0",
+			expectedRegion:           "us-west-2",
+			expectedAvailabilityZone: "us-west-2c",
+		},
+		{
+			name:       "fail: provider ID of another cloud",
+			providerID: "gce://project/us-central1-a/instance",
+			expectErr:  true,
+		},
+		{
+			name:       "fail: no availability zone",
+			providerID: "aws:////i-0123456789abcdef0",
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node-1",
+					Labels: tc.labels,
+				},
+				Spec: corev1.NodeSpec{
+					ProviderID: tc.providerID,
+				},
+			}
+			clientset := fake.NewSimpleClientset(node)
+
+			m, err := NewKubernetesMetadataService(clientset, "node-1")
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("NewKubernetesMetadataService() failed: expected error, got nothing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKubernetesMetadataService() failed: expected no error, got %v", err)
+			}
+
+			if m.GetInstanceID() != tc.expectedInstanceID {
+				t.Fatalf("GetInstanceID() failed: expected %v, got %v", tc.expectedInstanceID, m.GetInstanceID())
+			}
+
+			if m.GetRegion() != tc.expectedRegion {
+				t.Fatalf("GetRegion() failed: expected %v, got %v", tc.expectedRegion, m.GetRegion())
+			}
+
+			if m.GetAvailabilityZone() != tc.expectedAvailabilityZone {
+				t.Fatalf("GetAvailabilityZone() failed: expected %v, got %v", tc.expectedAvailabilityZone, m.GetAvailabilityZone())
+			}
+		})
+	}
+}
+
+func TestNewKubernetesMetadataServiceNodeNotFound(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	if _, err := NewKubernetesMetadataService(clientset, "node-1"); err == nil {
+		t.Fatal("NewKubernetesMetadataService() failed: expected error, got nothing")
+	}
+}
+
+func TestNewEnvMetadataService(t *testing.T) {
+	for _, key := range []string{InstanceIDEnvVar, RegionEnvVar, AvailabilityZoneEnvVar} {
+		defer os.Setenv(key, os.Getenv(key))
+	}
+
+	os.Setenv(InstanceIDEnvVar, stdInstanceID)
+	os.Setenv(RegionEnvVar, stdRegion)
+	os.Setenv(AvailabilityZoneEnvVar, stdAvailabilityZone)
+	m, err := NewEnvMetadataService()
+	if err != nil {
+		t.Fatalf("NewEnvMetadataService() failed: expected no error, got %v", err)
+	}
+	if m.GetInstanceID() != stdInstanceID || m.GetRegion() != stdRegion || m.GetAvailabilityZone() != stdAvailabilityZone {
+		t.Fatalf("NewEnvMetadataService() failed: got %v, %v, %v", m.GetInstanceID(), m.GetRegion(), m.GetAvailabilityZone())
+	}
+
+	os.Unsetenv(AvailabilityZoneEnvVar)
+	if _, err := NewEnvMetadataService(); err == nil {
+		t.Fatal("NewEnvMetadataService() failed: expected error when availability zone is not set, got nothing")
+	}
+}
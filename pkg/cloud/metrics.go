@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "fsx_csi"
+
+var (
+	apiRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "aws_api",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of AWS API requests, including retries, by service and operation.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"service", "operation"},
+	)
+	apiRequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "aws_api",
+			Name:      "request_errors_total",
+			Help:      "Number of AWS API requests that failed after all retries, by service, operation and error code.",
+		},
+		[]string{"service", "operation", "code"},
+	)
+	fileSystemWaitSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "filesystem_wait_seconds",
+			Help:      "Time spent so far waiting for a filesystem to become available, by filesystem.",
+		},
+		[]string{"filesystem_id"},
+	)
+	fileSystemWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "filesystem_wait_duration_seconds",
+			Help:      "Time spent waiting for a filesystem to become available, by result.",
+			Buckets:   []float64{30, 60, 120, 300, 600, 900, 1200, 1800, 3600},
+		},
+		[]string{"result"},
+	)
+)
+
+// RegisterMetrics registers the metrics of AWS API requests and of waits for
+// filesystems with r
+func RegisterMetrics(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		apiRequestDuration,
+		apiRequestErrors,
+		fileSystemWaitSeconds,
+		fileSystemWaitDuration,
+	} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newMetricsHandler returns a handler that records the latency of completed
+// requests, measured from their creation so that retries and rate limiting
+// are included, and the error code of failed requests.
+func newMetricsHandler() request.NamedHandler {
+	return request.NamedHandler{
+		Name: "fsx.csi.aws.com/Metrics",
+		Fn: func(req *request.Request) {
+			service := req.ClientInfo.ServiceName
+			operation := req.Operation.Name
+			apiRequestDuration.WithLabelValues(service, operation).Observe(time.Since(req.Time).Seconds())
+			if req.Error != nil {
+				code := "Unknown"
+				if awsErr, ok := req.Error.(awserr.Error); ok {
+					code = awsErr.Code()
+				}
+				apiRequestErrors.WithLabelValues(service, operation, code).Inc()
+			}
+		},
+	}
+}
+
+// fileSystemWait tracks the time spent waiting for a filesystem to become
+// available
+type fileSystemWait struct {
+	fileSystemId string
+	start        time.Time
+}
+
+func startFileSystemWait(fileSystemId string) *fileSystemWait {
+	fileSystemWaitSeconds.WithLabelValues(fileSystemId).Set(0)
+	return &fileSystemWait{
+		fileSystemId: fileSystemId,
+		start:        time.Now(),
+	}
+}
+
+// update sets the time spent so far waiting for the filesystem
+func (w *fileSystemWait) update() {
+	fileSystemWaitSeconds.WithLabelValues(w.fileSystemId).Set(time.Since(w.start).Seconds())
+}
+
+// done records the total time spent waiting for the filesystem, and stops
+// reporting the filesystem as being waited for
+func (w *fileSystemWait) done(err error) {
+	result := "available"
+	if err != nil {
+		result = "failed"
+	}
+	fileSystemWaitDuration.WithLabelValues(result).Observe(time.Since(w.start).Seconds())
+	fileSystemWaitSeconds.DeleteLabelValues(w.fileSystemId)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	clientmetadata "github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		expCode string
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:    "aws error",
+			err:     awserr.New("BadRequest", "", nil),
+			expCode: "BadRequest",
+		},
+		{
+			name:    "other error",
+			err:     errors.New("failed"),
+			expCode: "Unknown",
+		},
+	}
+
+	handler := newMetricsHandler()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &request.Request{
+				ClientInfo: clientmetadata.ClientInfo{ServiceName: "fsx"},
+				Operation:  &request.Operation{Name: "DescribeFileSystems"},
+				Time:       time.Now(),
+				Error:      tc.err,
+			}
+			var errorCount float64
+			if tc.expCode != "" {
+				errorCount = testutil.ToFloat64(apiRequestErrors.WithLabelValues("fsx", "DescribeFileSystems", tc.expCode))
+			}
+
+			handler.Fn(req)
+
+			if tc.expCode != "" {
+				if count := testutil.ToFloat64(apiRequestErrors.WithLabelValues("fsx", "DescribeFileSystems", tc.expCode)); count != errorCount+1 {
+					t.Fatalf("Expected %v errors, got %v", errorCount+1, count)
+				}
+			}
+		})
+	}
+}
+
+func TestFileSystemWait(t *testing.T) {
+	fsWait := startFileSystemWait("fs-1234")
+	fsWait.start = fsWait.start.Add(-time.Minute)
+	fsWait.update()
+	if seconds := testutil.ToFloat64(fileSystemWaitSeconds.WithLabelValues("fs-1234")); seconds < 60 {
+		t.Fatalf("Expected at least 60 seconds of waiting, got %v", seconds)
+	}
+
+	fsWait.done(nil)
+	if fileSystemWaitSeconds.DeleteLabelValues("fs-1234") {
+		t.Fatalf("Expected filesystem to be no longer waited for")
+	}
+}
@@ -35,6 +35,66 @@ func (m *MockFSx) EXPECT() *MockFSxMockRecorder {
 	return m.recorder
 }
 
+// CreateBackupWithContext mocks base method
+func (m *MockFSx) CreateBackupWithContext(arg0 context.Context, arg1 *fsx.CreateBackupInput, arg2 ...request.Option) (*fsx.CreateBackupOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateBackupWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.CreateBackupOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBackupWithContext indicates an expected call of CreateBackupWithContext
+func (mr *MockFSxMockRecorder) CreateBackupWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBackupWithContext", reflect.TypeOf((*MockFSx)(nil).CreateBackupWithContext), varargs...)
+}
+
+// CreateDataRepositoryTaskWithContext mocks base method
+func (m *MockFSx) CreateDataRepositoryTaskWithContext(arg0 context.Context, arg1 *fsx.CreateDataRepositoryTaskInput, arg2 ...request.Option) (*fsx.CreateDataRepositoryTaskOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateDataRepositoryTaskWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.CreateDataRepositoryTaskOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDataRepositoryTaskWithContext indicates an expected call of CreateDataRepositoryTaskWithContext
+func (mr *MockFSxMockRecorder) CreateDataRepositoryTaskWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDataRepositoryTaskWithContext", reflect.TypeOf((*MockFSx)(nil).CreateDataRepositoryTaskWithContext), varargs...)
+}
+
+// CreateFileSystemFromBackupWithContext mocks base method
+func (m *MockFSx) CreateFileSystemFromBackupWithContext(arg0 context.Context, arg1 *fsx.CreateFileSystemFromBackupInput, arg2 ...request.Option) (*fsx.CreateFileSystemFromBackupOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateFileSystemFromBackupWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.CreateFileSystemFromBackupOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFileSystemFromBackupWithContext indicates an expected call of CreateFileSystemFromBackupWithContext
+func (mr *MockFSxMockRecorder) CreateFileSystemFromBackupWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFileSystemFromBackupWithContext", reflect.TypeOf((*MockFSx)(nil).CreateFileSystemFromBackupWithContext), varargs...)
+}
+
 // CreateFileSystemWithContext mocks base method
 func (m *MockFSx) CreateFileSystemWithContext(arg0 context.Context, arg1 *fsx.CreateFileSystemInput, arg2 ...request.Option) (*fsx.CreateFileSystemOutput, error) {
 	m.ctrl.T.Helper()
@@ -55,6 +115,26 @@ func (mr *MockFSxMockRecorder) CreateFileSystemWithContext(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFileSystemWithContext", reflect.TypeOf((*MockFSx)(nil).CreateFileSystemWithContext), varargs...)
 }
 
+// DeleteBackupWithContext mocks base method
+func (m *MockFSx) DeleteBackupWithContext(arg0 context.Context, arg1 *fsx.DeleteBackupInput, arg2 ...request.Option) (*fsx.DeleteBackupOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteBackupWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.DeleteBackupOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBackupWithContext indicates an expected call of DeleteBackupWithContext
+func (mr *MockFSxMockRecorder) DeleteBackupWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBackupWithContext", reflect.TypeOf((*MockFSx)(nil).DeleteBackupWithContext), varargs...)
+}
+
 // DeleteFileSystemWithContext mocks base method
 func (m *MockFSx) DeleteFileSystemWithContext(arg0 context.Context, arg1 *fsx.DeleteFileSystemInput, arg2 ...request.Option) (*fsx.DeleteFileSystemOutput, error) {
 	m.ctrl.T.Helper()
@@ -75,6 +155,46 @@ func (mr *MockFSxMockRecorder) DeleteFileSystemWithContext(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFileSystemWithContext", reflect.TypeOf((*MockFSx)(nil).DeleteFileSystemWithContext), varargs...)
 }
 
+// DescribeBackupsWithContext mocks base method
+func (m *MockFSx) DescribeBackupsWithContext(arg0 context.Context, arg1 *fsx.DescribeBackupsInput, arg2 ...request.Option) (*fsx.DescribeBackupsOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeBackupsWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.DescribeBackupsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeBackupsWithContext indicates an expected call of DescribeBackupsWithContext
+func (mr *MockFSxMockRecorder) DescribeBackupsWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeBackupsWithContext", reflect.TypeOf((*MockFSx)(nil).DescribeBackupsWithContext), varargs...)
+}
+
+// DescribeDataRepositoryTasksWithContext mocks base method
+func (m *MockFSx) DescribeDataRepositoryTasksWithContext(arg0 context.Context, arg1 *fsx.DescribeDataRepositoryTasksInput, arg2 ...request.Option) (*fsx.DescribeDataRepositoryTasksOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeDataRepositoryTasksWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.DescribeDataRepositoryTasksOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeDataRepositoryTasksWithContext indicates an expected call of DescribeDataRepositoryTasksWithContext
+func (mr *MockFSxMockRecorder) DescribeDataRepositoryTasksWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeDataRepositoryTasksWithContext", reflect.TypeOf((*MockFSx)(nil).DescribeDataRepositoryTasksWithContext), varargs...)
+}
+
 // DescribeFileSystemsWithContext mocks base method
 func (m *MockFSx) DescribeFileSystemsWithContext(arg0 context.Context, arg1 *fsx.DescribeFileSystemsInput, arg2 ...request.Option) (*fsx.DescribeFileSystemsOutput, error) {
 	m.ctrl.T.Helper()
@@ -94,3 +214,23 @@ func (mr *MockFSxMockRecorder) DescribeFileSystemsWithContext(arg0, arg1 interfa
 	varargs := append([]interface{}{arg0, arg1}, arg2...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeFileSystemsWithContext", reflect.TypeOf((*MockFSx)(nil).DescribeFileSystemsWithContext), varargs...)
 }
+
+// UpdateFileSystemWithContext mocks base method
+func (m *MockFSx) UpdateFileSystemWithContext(arg0 context.Context, arg1 *fsx.UpdateFileSystemInput, arg2 ...request.Option) (*fsx.UpdateFileSystemOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateFileSystemWithContext", varargs...)
+	ret0, _ := ret[0].(*fsx.UpdateFileSystemOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateFileSystemWithContext indicates an expected call of UpdateFileSystemWithContext
+func (mr *MockFSxMockRecorder) UpdateFileSystemWithContext(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFileSystemWithContext", reflect.TypeOf((*MockFSx)(nil).UpdateFileSystemWithContext), varargs...)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"golang.org/x/time/rate"
+	"k8s.io/klog"
+)
+
+const (
+	retryerMinRetryDelay    = 100 * time.Millisecond
+	retryerMaxRetryDelay    = 5 * time.Second
+	retryerMinThrottleDelay = 1 * time.Second
+	retryerMaxThrottleDelay = 60 * time.Second
+)
+
+// serviceUnavailableCodes are the error codes FSx and EC2 return when they
+// are overloaded, which are retried like throttling errors.
+var serviceUnavailableCodes = map[string]bool{
+	"ServiceUnavailable":          true,
+	"ServiceUnavailableException": true,
+	"InternalServerError":         true,
+}
+
+// retryer retries failed AWS requests with exponential backoff and jitter.
+// Throttled requests, and requests failed because the service is
+// unavailable, are retried with longer delays than other retryable errors.
+type retryer struct {
+	maxRetries int
+}
+
+func newRetryer(maxRetries int) *retryer {
+	return &retryer{
+		maxRetries: maxRetries,
+	}
+}
+
+func (r *retryer) MaxRetries() int {
+	return r.maxRetries
+}
+
+func (r *retryer) ShouldRetry(req *request.Request) bool {
+	if req.Retryable != nil {
+		return *req.Retryable
+	}
+	return isThrottlingError(req) || req.IsErrorRetryable()
+}
+
+func (r *retryer) RetryRules(req *request.Request) time.Duration {
+	minDelay, maxDelay := retryerMinRetryDelay, retryerMaxRetryDelay
+	if isThrottlingError(req) {
+		minDelay, maxDelay = retryerMinThrottleDelay, retryerMaxThrottleDelay
+		klog.V(4).Infof("AWS request is throttled, retry %d: %v", req.RetryCount+1, req.Error)
+	}
+	return backoff(req.RetryCount, minDelay, maxDelay)
+}
+
+// backoff returns a random delay between half and the whole of minDelay
+// doubled retryCount times, capped to maxDelay.
+func backoff(retryCount int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := maxDelay
+	if retryCount < 32 && minDelay<<uint(retryCount) < maxDelay {
+		delay = minDelay << uint(retryCount)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func isThrottlingError(req *request.Request) bool {
+	if req.IsErrorThrottle() {
+		return true
+	}
+	if awsErr, ok := req.Error.(awserr.Error); ok {
+		return serviceUnavailableCodes[awsErr.Code()]
+	}
+	return false
+}
+
+// newRateLimitHandler returns a handler that delays requests, including
+// their retries, until the limiter allows them to be sent.
+func newRateLimitHandler(limiter *rate.Limiter) request.NamedHandler {
+	return request.NamedHandler{
+		Name: "fsx.csi.aws.com/RateLimit",
+		Fn: func(req *request.Request) {
+			if err := limiter.Wait(req.Context()); err != nil {
+				req.Error = awserr.New(request.CanceledErrorCode, "rate limited request canceled", err)
+			}
+		},
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/fsx"
+)
+
+func TestRetryerShouldRetry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		statusCode  int
+		expRetry    bool
+		expThrottle bool
+	}{
+		{
+			name:        "throttling exception",
+			err:         awserr.New("ThrottlingException", "Rate exceeded", nil),
+			expRetry:    true,
+			expThrottle: true,
+		},
+		{
+			name:        "request limit exceeded",
+			err:         awserr.New("RequestLimitExceeded", "Request limit exceeded", nil),
+			expRetry:    true,
+			expThrottle: true,
+		},
+		{
+			name:        "service unavailable",
+			err:         awserr.New("ServiceUnavailable", "", nil),
+			expRetry:    true,
+			expThrottle: true,
+		},
+		{
+			name:        "service unavailable status",
+			err:         awserr.New("Unknown", "", nil),
+			statusCode:  http.StatusServiceUnavailable,
+			expRetry:    true,
+			expThrottle: true,
+		},
+		{
+			name:       "internal server error status",
+			err:        awserr.New("Unknown", "", nil),
+			statusCode: http.StatusInternalServerError,
+			expRetry:   true,
+		},
+		{
+			name: "filesystem not found",
+			err:  awserr.New(fsx.ErrCodeFileSystemNotFound, "", nil),
+		},
+		{
+			name: "bad request",
+			err:  awserr.New(fsx.ErrCodeBadRequest, "", nil),
+		},
+	}
+
+	r := newRetryer(DefaultMaxRetries)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &request.Request{
+				Error: tc.err,
+			}
+			if tc.statusCode != 0 {
+				req.HTTPResponse = &http.Response{StatusCode: tc.statusCode}
+			}
+
+			if retry := r.ShouldRetry(req); retry != tc.expRetry {
+				t.Fatalf("ShouldRetry mismatches. actual: %v expected: %v", retry, tc.expRetry)
+			}
+
+			if throttle := isThrottlingError(req); throttle != tc.expThrottle {
+				t.Fatalf("isThrottlingError mismatches. actual: %v expected: %v", throttle, tc.expThrottle)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	var (
+		minDelay = 1 * time.Second
+		maxDelay = 60 * time.Second
+	)
+	testCases := []struct {
+		retryCount int
+		expMax     time.Duration
+	}{
+		{retryCount: 0, expMax: 1 * time.Second},
+		{retryCount: 1, expMax: 2 * time.Second},
+		{retryCount: 3, expMax: 8 * time.Second},
+		{retryCount: 6, expMax: 60 * time.Second},
+		{retryCount: 100, expMax: 60 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < 100; i++ {
+			delay := backoff(tc.retryCount, minDelay, maxDelay)
+			if delay < tc.expMax/2 || delay > tc.expMax {
+				t.Fatalf("Delay of retry %d is out of range. actual: %v expected: [%v, %v]", tc.retryCount, delay, tc.expMax/2, tc.expMax)
+			}
+		}
+	}
+}
@@ -19,10 +19,14 @@ package driver
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/fsx"
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/util"
 	"google.golang.org/grpc/codes"
@@ -34,20 +38,41 @@ var (
 	// controllerCaps represents the capability of controller service
 	controllerCaps = []csi.ControllerServiceCapability_RPC_Type{
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
 	}
 )
 
 const (
 	sharedVolumeIdPrefix = "shared"
 
-	volumeContextDnsName      = "dnsname"
-	volumeContextMountName    = "mountname"
-	volumeContextSubPath      = "subPath"
-	volumeContextFileSystemId = "fileSystemId"
+	// createVolumeWaitTimeout is how long CreateVolume waits for a new
+	// filesystem to become available. It is only long enough to check the
+	// filesystem once, since the request would hold a provisioner worker
+	// while FSx takes minutes to create it.
+	createVolumeWaitTimeout = 5 * time.Second
+
+	volumeContextDnsName            = "dnsname"
+	volumeContextMountName          = "mountname"
+	volumeContextSubPath            = "subPath"
+	volumeContextFileSystemId       = "fileSystemId"
+	volumeContextLustreMountOptions = "lustreMountOptions"
+	volumeContextMaxCachedMB        = "maxCachedMB"
+	volumeContextMaxRpcsInFlight    = "maxRpcsInFlight"
+	volumeContextChecksums          = "checksums"
 
 	volumeParamsFileSystemId                  = "fileSystemId"
 	volumeParamsSubnetId                      = "subnetId"
 	volumeParamsSecurityGroupIds              = "securityGroupIds"
+	volumeParamsSubnetTags                    = "subnetTags"
+	volumeParamsSecurityGroupTags             = "securityGroupTags"
+	volumeParamsSecurityGroupNames            = "securityGroupNames"
+	volumeParamsPinToZone                     = "pinToZone"
+	volumeParamsExportOnDelete                = "exportOnDelete"
 	volumeParamsAutoImportPolicy              = "autoImportPolicy"
 	volumeParamsS3ImportPath                  = "s3ImportPath"
 	volumeParamsS3ExportPath                  = "s3ExportPath"
@@ -59,10 +84,15 @@ const (
 	volumeParamsAutomaticBackupRetentionDays  = "automaticBackupRetentionDays"
 	volumeParamsDailyAutomaticBackupStartTime = "dailyAutomaticBackupStartTime"
 	volumeParamsCopyTagsToBackups             = "copyTagsToBackups"
+	volumeParamsLustreMountOptions            = "lustreMountOptions"
+	volumeParamsSubPathUid                    = "subPathUid"
+	volumeParamsSubPathGid                    = "subPathGid"
+	volumeParamsSubPathMode                   = "subPathMode"
+	volumeParamsSubPathReclaimPolicy          = "subPathReclaimPolicy"
+	volumeParamsTags                          = "tags"
 )
 
 func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	klog.V(4).Infof("CreateVolume: called with args %#v", req)
 	volName := req.GetName()
 	if len(volName) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume name not provided")
@@ -76,41 +106,158 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	if !d.isValidVolumeCapabilities(volCaps) {
 		return nil, status.Error(codes.InvalidArgument, "Volume capabilities not supported")
 	}
+	// concurrent requests for the same volume would race on the filesystem
+	// being created, so only one is served at a time and the others are
+	// retried by the provisioner
+	if !d.inFlight.Insert(volName) {
+		return nil, status.Errorf(codes.Aborted, "Create volume request for %q is already in progress", volName)
+	}
+	defer d.inFlight.Delete(volName)
+
+	// filesystems are managed with the credentials in the provisioner
+	// secrets if any, and their volume ID then records their account
+	c, credentialed, err := d.cloudForSecrets(req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
+
 	// create a new volume with idempotency
 	// idempotency is handled by `CreateFileSystem`
 	var (
-		fs  *cloud.FileSystem
-		err error
+		fs                 *cloud.FileSystem
+		accessibleTopology []*csi.Topology
 	)
 	volumeParams := req.GetParameters()
+	lustreOptions, err := parseLustreMountOptions(volumeParams[volumeParamsLustreMountOptions])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid %s: %v", volumeParamsLustreMountOptions, err)
+	}
+	// the Lustre client parameters are passed to the node in the volume
+	// context under the same keys
+	lustreParams, err := parseLustreParams(volumeParams)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid Lustre parameter: %v", err)
+	}
 	fileSystemId := volumeParams[volumeParamsFileSystemId]
+	var pathOptions *subPathOptions
 	if fileSystemId != "" {
-		fs, err = d.cloud.DescribeFileSystem(ctx, fileSystemId)
+		if req.GetVolumeContentSource() != nil {
+			return nil, status.Error(codes.InvalidArgument, "Volume content source is not supported for shared volumes")
+		}
+		if err := validateSubPath(volName); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Volume name %q can not be a subpath", volName)
+		}
+		pathOptions, err = parseSubPathOptions(volumeParams)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		fs, err = c.DescribeFileSystem(ctx, fileSystemId)
+	} else if pending := d.inFlight.GetPending(req); pending != nil {
+		klog.V(4).Infof("CreateVolume: resuming wait for filesystem %s of volume %s", pending.fileSystemId, volName)
+		fs, err = c.DescribeFileSystem(ctx, pending.fileSystemId)
+		if err != nil {
+			d.inFlight.DeletePending(volName)
+			return nil, status.Errorf(codes.Internal, "Could not describe filesystem %q: %v", pending.fileSystemId, err)
+		}
+		accessibleTopology = pending.accessibleTopology
 	} else {
-		fs, err = d.createVolumeFromRequest(ctx, req)
+		fs, accessibleTopology, err = d.createVolumeFromRequest(ctx, c, req)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = d.cloud.WaitForFileSystemAvailable(ctx, fs.FileSystemId)
+	// FSx takes several minutes to create a filesystem, longer than the
+	// provisioner waits for, so rather than blocking until it is available
+	// the request fails and its retry resumes the wait
+	waitCtx, cancel := context.WithTimeout(ctx, createVolumeWaitTimeout)
+	defer cancel()
+	err = c.WaitForFileSystemAvailable(waitCtx, fs.FileSystemId)
 	if err != nil {
+		if waitCtx.Err() != nil {
+			if fileSystemId == "" {
+				d.inFlight.SetPending(newPendingVolume(req, fs.FileSystemId, accessibleTopology))
+			}
+			return nil, status.Errorf(codes.DeadlineExceeded, "Filesystem %s is still being created", fs.FileSystemId)
+		}
+		d.inFlight.DeletePending(volName)
 		return nil, status.Errorf(codes.Internal, "Filesystem is not ready: %v", err)
 	}
+	d.inFlight.DeletePending(volName)
+
+	accountId := ""
+	if credentialed {
+		accountId = fs.OwnerId
+	}
+
+	var resp *csi.CreateVolumeResponse
 	if fileSystemId != "" {
-		return newCreateVolumeResponseWithSubPath(volName, fs), nil
+		// the volume is limited to the requested capacity with a project
+		// quota, without a capacity it can use the whole filesystem
+		quotaBytes := req.GetCapacityRange().GetRequiredBytes()
+		if quotaBytes == 0 {
+			quotaBytes = req.GetCapacityRange().GetLimitBytes()
+		}
+		if err := d.createSubPath(fs, volName, pathOptions, quotaBytes); err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not create subpath of filesystem %q: %v", fileSystemId, err)
+		}
+		v := &sharedVolume{
+			fileSystemId:  fs.FileSystemId,
+			accountId:     accountId,
+			subPath:       volName,
+			reclaimPolicy: pathOptions.reclaimPolicy,
+		}
+		resp = newCreateVolumeResponseWithSubPath(v, fs, quotaBytes)
 	} else {
-		return newCreateVolumeResponse(fs), nil
+		resp = newCreateVolumeResponse(fs, accountId, req.GetVolumeContentSource(), accessibleTopology)
+	}
+	if len(lustreOptions) > 0 {
+		resp.Volume.VolumeContext[volumeContextLustreMountOptions] = strings.Join(lustreOptions, ",")
 	}
+	for key, val := range lustreParams {
+		resp.Volume.VolumeContext[key] = val
+	}
+	return resp, nil
 }
 
-func (d *Driver) createVolumeFromRequest(ctx context.Context, req *csi.CreateVolumeRequest) (*cloud.FileSystem, error) {
+func (d *Driver) createVolumeFromRequest(ctx context.Context, c cloud.Cloud, req *csi.CreateVolumeRequest) (*cloud.FileSystem, []*csi.Topology, error) {
 	volumeParams := req.GetParameters()
-	subnetId := volumeParams[volumeParamsSubnetId]
-	securityGroupIds := volumeParams[volumeParamsSecurityGroupIds]
+	// FSx for Lustre is reachable from every zone of the VPC, so the volume
+	// is only restricted to the zone of its subnet when asked for.
+	pinToZone := false
+	if val, ok := volumeParams[volumeParamsPinToZone]; ok {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, "pinToZone must be a bool")
+		}
+		pinToZone = b
+	}
+	subnet, err := d.chooseSubnet(ctx, c, volumeParams, req.GetAccessibilityRequirements(), pinToZone)
+	if err != nil {
+		return nil, nil, err
+	}
+	securityGroupIds, err := d.chooseSecurityGroups(ctx, c, volumeParams, subnet)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := d.volumeTags(volumeParams)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "Invalid tags: %v", err)
+	}
 	fsOptions := &cloud.FileSystemOptions{
-		SubnetId:         subnetId,
-		SecurityGroupIds: strings.Split(securityGroupIds, ","),
+		SubnetId:         subnet.SubnetId,
+		SecurityGroupIds: securityGroupIds,
+		ClusterId:        d.clusterId,
+		Tags:             tags,
+	}
+
+	var accessibleTopology []*csi.Topology
+	if pinToZone && subnet.AvailabilityZone != "" {
+		accessibleTopology = []*csi.Topology{
+			{
+				Segments: map[string]string{TopologyKey: subnet.AvailabilityZone},
+			},
+		}
 	}
 
 	if val, ok := volumeParams[volumeParamsAutoImportPolicy]; ok {
@@ -140,7 +287,7 @@ func (d *Driver) createVolumeFromRequest(ctx context.Context, req *csi.CreateVol
 	if val, ok := volumeParams[volumeParamsAutomaticBackupRetentionDays]; ok {
 		n, err := strconv.ParseInt(val, 10, 64)
 		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "automaticBackupRetentionDays must be a number")
+			return nil, nil, status.Error(codes.InvalidArgument, "automaticBackupRetentionDays must be a number")
 		}
 		fsOptions.AutomaticBackupRetentionDays = n
 	}
@@ -148,11 +295,22 @@ func (d *Driver) createVolumeFromRequest(ctx context.Context, req *csi.CreateVol
 	if val, ok := volumeParams[volumeParamsCopyTagsToBackups]; ok {
 		b, err := strconv.ParseBool(val)
 		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "copyTagsToBackups must be a bool")
+			return nil, nil, status.Error(codes.InvalidArgument, "copyTagsToBackups must be a bool")
 		}
 		fsOptions.CopyTagsToBackups = b
 	}
 
+	if val, ok := volumeParams[volumeParamsExportOnDelete]; ok {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, "exportOnDelete must be a bool")
+		}
+		if b && fsOptions.S3ImportPath == "" {
+			return nil, nil, status.Error(codes.InvalidArgument, "exportOnDelete requires s3ImportPath")
+		}
+		fsOptions.ExportOnDelete = b
+	}
+
 	if val, ok := volumeParams[volumeParamsStorageType]; ok {
 		fsOptions.StorageType = val
 	}
@@ -164,46 +322,241 @@ func (d *Driver) createVolumeFromRequest(ctx context.Context, req *csi.CreateVol
 	if val, ok := volumeParams[volumeParamsPerUnitStorageThroughput]; ok {
 		n, err := strconv.ParseInt(val, 10, 64)
 		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "perUnitStorageThroughput must be a number")
+			return nil, nil, status.Error(codes.InvalidArgument, "perUnitStorageThroughput must be a number")
 		}
 		fsOptions.PerUnitStorageThroughput = n
 	}
 
+	if volumeSource := req.GetVolumeContentSource(); volumeSource != nil {
+		snapshotSource := volumeSource.GetSnapshot()
+		if snapshotSource == nil {
+			return nil, nil, status.Error(codes.InvalidArgument, "Unsupported volume content source")
+		}
+		if len(snapshotSource.GetSnapshotId()) == 0 {
+			return nil, nil, status.Error(codes.InvalidArgument, "Snapshot ID not provided in volume content source")
+		}
+		if fsOptions.KmsKeyId != "" {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "%s is not supported when restoring from a snapshot", volumeParamsKmsKeyId)
+		}
+		// the backup is in the account of the credentials the filesystem
+		// is created with
+		fsOptions.BackupId, _ = splitAccountId(snapshotSource.GetSnapshotId())
+	}
+
 	capRange := req.GetCapacityRange()
 	if capRange == nil {
 		fsOptions.CapacityGiB = cloud.DefaultVolumeSize
 	} else {
 		fsOptions.CapacityGiB = util.RoundUpVolumeSize(capRange.GetRequiredBytes(), fsOptions.DeploymentType, fsOptions.StorageType, fsOptions.PerUnitStorageThroughput)
+		fsOptions.CapacityLimitGiB = capRange.GetLimitBytes() / util.GiB
 	}
 	volName := req.GetName()
-	fs, err := d.cloud.CreateFileSystem(ctx, volName, fsOptions)
+	fs, err := c.CreateFileSystem(ctx, volName, fsOptions)
 	if err != nil {
 		switch err {
 		case cloud.ErrFsExistsDiffSize:
-			return nil, status.Error(codes.AlreadyExists, err.Error())
+			return nil, nil, status.Error(codes.AlreadyExists, err.Error())
+		case cloud.ErrNotFound:
+			return nil, nil, status.Errorf(codes.NotFound, "Snapshot %q not found", fsOptions.BackupId)
+		case cloud.ErrBackupTooSmall, cloud.ErrBackupTooLarge:
+			return nil, nil, status.Error(codes.OutOfRange, err.Error())
+		case cloud.ErrBackupIncompatible:
+			return nil, nil, status.Error(codes.InvalidArgument, err.Error())
 		default:
-			return nil, status.Errorf(codes.Internal, "Could not create volume %q: %v", volName, err)
+			return nil, nil, status.Errorf(codes.Internal, "Could not create volume %q: %v", volName, err)
+		}
+	}
+	return fs, accessibleTopology, nil
+}
+
+// chooseSubnet returns the subnet to create a filesystem in. The candidates
+// are the subnets listed in the parameters or, when none is listed, the
+// subnets of the controller's VPC matching the subnet tags. When the request
+// has accessibility requirements, the first candidate in the most preferred
+// zone is chosen. Otherwise, or if no candidate is in an accessible zone and
+// the volume isn't pinned to its zone, the first listed subnet is chosen, or
+// the first discovered subnet in the controller's zone. Subnets are looked up
+// with the cloud the filesystem is created with, in the VPC of the controller
+// which is looked up with the cloud of the controller.
+func (d *Driver) chooseSubnet(ctx context.Context, c cloud.Cloud, volumeParams map[string]string, requirement *csi.TopologyRequirement, pinToZone bool) (*cloud.Subnet, error) {
+	if val := volumeParams[volumeParamsSubnetId]; val != "" {
+		subnetIds := strings.Split(val, ",")
+		if len(subnetIds) == 1 && (requirement == nil || !pinToZone) {
+			return &cloud.Subnet{SubnetId: subnetIds[0]}, nil
+		}
+
+		subnets, err := c.DescribeSubnets(ctx, subnetIds)
+		if err != nil {
+			if err == cloud.ErrNotFound {
+				return nil, status.Errorf(codes.InvalidArgument, "Subnets %v not found", subnetIds)
+			}
+			return nil, status.Errorf(codes.Internal, "Could not get subnets %v: %v", subnetIds, err)
+		}
+		return pickSubnet(subnets, requirement, "", pinToZone)
+	}
+
+	tags, err := parseTagFilters(volumeParams[volumeParamsSubnetTags])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid %s: %v", volumeParamsSubnetTags, err)
+	}
+	if d.nodeID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s must be provided when the controller doesn't run on EC2", volumeParamsSubnetId)
+	}
+	vpcId, err := d.cloud.GetInstanceVpcId(ctx, d.nodeID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get VPC of instance %q: %v", d.nodeID, err)
+	}
+	subnets, err := c.FindSubnets(ctx, vpcId, tags)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.InvalidArgument, "No subnet in VPC %q matches tags %v", vpcId, tags)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not find subnets in VPC %q: %v", vpcId, err)
+	}
+	return pickSubnet(subnets, requirement, d.availabilityZone, pinToZone)
+}
+
+// pickSubnet returns the first subnet in the most preferred accessible zone.
+// Without accessibility requirements, or if no subnet is in an accessible
+// zone and the volume isn't pinned to its zone, it returns the first subnet
+// in defaultZone, or the first subnet if there is none.
+func pickSubnet(subnets []*cloud.Subnet, requirement *csi.TopologyRequirement, defaultZone string, pinToZone bool) (*cloud.Subnet, error) {
+	var topologies []*csi.Topology
+	topologies = append(topologies, requirement.GetPreferred()...)
+	topologies = append(topologies, requirement.GetRequisite()...)
+
+	constrained := false
+	for _, topology := range topologies {
+		zone, ok := topology.GetSegments()[TopologyKey]
+		if !ok {
+			continue
+		}
+		constrained = true
+		for _, subnet := range subnets {
+			if subnet.AvailabilityZone == zone {
+				return subnet, nil
+			}
+		}
+	}
+
+	if constrained && pinToZone {
+		var subnetIds []string
+		for _, subnet := range subnets {
+			subnetIds = append(subnetIds, subnet.SubnetId)
 		}
+		return nil, status.Errorf(codes.ResourceExhausted, "None of subnets %v is in an accessible availability zone", subnetIds)
 	}
-	return fs, nil
+	for _, subnet := range subnets {
+		if defaultZone != "" && subnet.AvailabilityZone == defaultZone {
+			return subnet, nil
+		}
+	}
+	return subnets[0], nil
+}
+
+// chooseSecurityGroups returns the security groups listed in the parameters
+// or, when none is listed, the security groups of the subnet's VPC matching
+// the security group tags and names.
+func (d *Driver) chooseSecurityGroups(ctx context.Context, c cloud.Cloud, volumeParams map[string]string, subnet *cloud.Subnet) ([]string, error) {
+	if val := volumeParams[volumeParamsSecurityGroupIds]; val != "" {
+		return strings.Split(val, ","), nil
+	}
+
+	tags, err := parseTagFilters(volumeParams[volumeParamsSecurityGroupTags])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid %s: %v", volumeParamsSecurityGroupTags, err)
+	}
+	var names []string
+	if val := volumeParams[volumeParamsSecurityGroupNames]; val != "" {
+		names = strings.Split(val, ",")
+	}
+	if len(tags) == 0 && len(names) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "One of %s, %s or %s must be provided", volumeParamsSecurityGroupIds, volumeParamsSecurityGroupTags, volumeParamsSecurityGroupNames)
+	}
+
+	vpcId := subnet.VpcId
+	if vpcId == "" {
+		subnets, err := c.DescribeSubnets(ctx, []string{subnet.SubnetId})
+		if err != nil {
+			if err == cloud.ErrNotFound {
+				return nil, status.Errorf(codes.InvalidArgument, "Subnet %q not found", subnet.SubnetId)
+			}
+			return nil, status.Errorf(codes.Internal, "Could not get subnet %q: %v", subnet.SubnetId, err)
+		}
+		vpcId = subnets[0].VpcId
+	}
+
+	securityGroupIds, err := c.FindSecurityGroups(ctx, vpcId, tags, names)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.InvalidArgument, "No security group in VPC %q matches tags %v and names %v", vpcId, tags, names)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not find security groups in VPC %q: %v", vpcId, err)
+	}
+	return securityGroupIds, nil
+}
+
+// parseTagFilters parses a comma separated list of key=value pairs. A key
+// without a value matches any value of the tag.
+func parseTagFilters(val string) (map[string]string, error) {
+	tags := map[string]string{}
+	if val == "" {
+		return tags, nil
+	}
+	for _, pair := range strings.Split(val, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, fmt.Errorf("tag %q has an empty key", pair)
+		}
+		tags[key] = ""
+		if len(kv) == 2 {
+			tags[key] = strings.TrimSpace(kv[1])
+		}
+	}
+	return tags, nil
 }
 
 func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
-	klog.V(4).Infof("DeleteVolume: called with args: %#v", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
 	}
 	// We don't have any metadata during the delete step, just the VolumeId.
 	// As such, we prefix volumes from a shared fSX volume with a prefix.
-	// Don't delete those, they are not managed by this driver.
+	// Don't delete the filesystem of those, it is not managed by this driver,
+	// only reclaim their subpath as their ID says.
 	if strings.HasPrefix(volumeID, sharedVolumeIdPrefix) {
-		// TODO: If filesystem still exists, we might want to remove the folder
-		klog.V(4).Infof("DeleteVolume: shared volume found, returning with success")
-		return &csi.DeleteVolumeResponse{}, nil
+		return d.deleteSharedVolume(ctx, volumeID, req.GetSecrets())
+	}
+
+	fileSystemId, accountId := splitAccountId(volumeID)
+	c, err := d.cloudForAccount(ctx, accountId, req.GetSecrets())
+	if err != nil {
+		return nil, err
 	}
 
-	if err := d.cloud.DeleteFileSystem(ctx, volumeID); err != nil {
+	fs, err := c.DescribeFileSystem(ctx, fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			klog.V(4).Infof("DeleteVolume: volume not found, returning with success")
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
+	}
+	// the ID may be of a filesystem the driver doesn't manage, e.g. one
+	// referenced by a static PV with the Delete reclaim policy
+	if err := d.checkOwnership(fs); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Refusing to delete volume %q: %v", volumeID, err)
+	}
+
+	if fs.Tags[cloud.ExportOnDeleteTagKey] == "true" {
+		if err := d.exportFileSystem(ctx, c, fs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.DeleteFileSystem(ctx, fileSystemId); err != nil {
 		if err == cloud.ErrNotFound {
 			klog.V(4).Infof("DeleteVolume: volume not found, returning with success")
 			return &csi.DeleteVolumeResponse{}, nil
@@ -213,6 +566,60 @@ func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest)
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
+// deleteSharedVolume reclaims the subpath of the shared volume as its
+// reclaim policy says, if its filesystem still exists.
+func (d *Driver) deleteSharedVolume(ctx context.Context, volumeID string, secrets map[string]string) (*csi.DeleteVolumeResponse, error) {
+	v, err := parseSharedVolumeId(volumeID)
+	if err != nil {
+		klog.V(4).Infof("DeleteVolume: %v, returning with success", err)
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+	if v.reclaimPolicy == subPathReclaimRetain {
+		klog.V(4).Infof("DeleteVolume: retaining subpath %s of filesystem %s", v.subPath, v.fileSystemId)
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	c, err := d.cloudForAccount(ctx, v.accountId, secrets)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := c.DescribeFileSystem(ctx, v.fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			klog.V(4).Infof("DeleteVolume: filesystem %s of shared volume not found, returning with success", v.fileSystemId)
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get filesystem %q: %v", v.fileSystemId, err)
+	}
+	if err := d.reclaimSubPath(fs, v); err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not reclaim volume %q: %v", volumeID, err)
+	}
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+// exportFileSystem exports the changes of the filesystem to its data
+// repository. As exports can take hours, it returns Aborted while the export
+// is still running, and the retried DeleteVolume waits for the same task.
+func (d *Driver) exportFileSystem(ctx context.Context, c cloud.Cloud, fs *cloud.FileSystem) error {
+	task, err := c.CreateExportTask(ctx, fs.FileSystemId)
+	if err != nil {
+		if err == cloud.ErrDataRepositoryTaskRunning {
+			return status.Errorf(codes.Aborted, "Another data repository task is running on volume %q", fs.FileSystemId)
+		}
+		return status.Errorf(codes.Internal, "Could not export volume %q: %v", fs.FileSystemId, err)
+	}
+	klog.Infof("DeleteVolume: exporting volume %s to its data repository with task %s", fs.FileSystemId, task.TaskId)
+
+	if err := c.WaitForDataRepositoryTask(ctx, task.TaskId); err != nil {
+		if err == cloud.ErrDataRepositoryTaskRunning {
+			return status.Errorf(codes.Aborted, "Export task %s of volume %q is still running", task.TaskId, fs.FileSystemId)
+		}
+		return status.Errorf(codes.Internal, "Export task %s of volume %q failed: %v", task.TaskId, fs.FileSystemId, err)
+	}
+	klog.Infof("DeleteVolume: export task %s of volume %s succeeded", task.TaskId, fs.FileSystemId)
+	return nil
+}
+
 func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "")
 }
@@ -222,7 +629,6 @@ func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.Control
 }
 
 func (d *Driver) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
-	klog.V(4).Infof("ControllerGetCapabilities: called with args %#v", req)
 	var caps []*csi.ControllerServiceCapability
 	for _, cap := range controllerCaps {
 		c := &csi.ControllerServiceCapability{
@@ -238,17 +644,96 @@ func (d *Driver) ControllerGetCapabilities(ctx context.Context, req *csi.Control
 }
 
 func (d *Driver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	klog.V(4).Infof("GetCapacity: called with args %#v", req)
 	return nil, status.Error(codes.Unimplemented, "")
 }
 
 func (d *Driver) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	klog.V(4).Infof("ListVolumes: called with args %#v", req)
-	return nil, status.Error(codes.Unimplemented, "")
+	fileSystems, err := d.cloud.DescribeFileSystems(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not list volumes: %v", err)
+	}
+
+	// Only list the filesystems that were provisioned by the driver. Shared
+	// volumes can't be listed since nothing is recorded about them in FSx.
+	var volumes []*cloud.FileSystem
+	for _, fs := range fileSystems {
+		if _, ok := fs.Tags[cloud.VolumeNameTagKey]; ok {
+			volumes = append(volumes, fs)
+		}
+	}
+
+	// Sort the volumes so that the tokens handed out stay valid across calls
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].FileSystemId < volumes[j].FileSystemId
+	})
+
+	start, end, nextToken, err := getPageBounds(len(volumes), req.GetMaxEntries(), req.GetStartingToken())
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*csi.ListVolumesResponse_Entry
+	for _, fs := range volumes[start:end] {
+		entries = append(entries, &csi.ListVolumesResponse_Entry{
+			Volume: newCreateVolumeResponse(fs, "", nil, nil).Volume,
+			Status: &csi.ListVolumesResponse_VolumeStatus{
+				VolumeCondition: newVolumeCondition(fs),
+			},
+		})
+	}
+
+	return &csi.ListVolumesResponse{
+		Entries:   entries,
+		NextToken: nextToken,
+	}, nil
+}
+
+func (d *Driver) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	// The health of a shared volume is the health of the filesystem it is part of
+	fileSystemId, accountId := splitAccountId(volumeID)
+	var shared *sharedVolume
+	if strings.HasPrefix(volumeID, sharedVolumeIdPrefix) {
+		var err error
+		shared, err = parseSharedVolumeId(volumeID)
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+		}
+		fileSystemId, accountId = shared.fileSystemId, shared.accountId
+	}
+
+	c, err := d.cloudForAccount(ctx, accountId, nil)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := c.DescribeFileSystem(ctx, fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
+	}
+
+	var volume *csi.Volume
+	if shared != nil {
+		volume = newCreateVolumeResponseWithSubPath(shared, fs, 0).Volume
+	} else {
+		volume = newCreateVolumeResponse(fs, accountId, nil, nil).Volume
+	}
+
+	return &csi.ControllerGetVolumeResponse{
+		Volume: volume,
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			VolumeCondition: newVolumeCondition(fs),
+		},
+	}, nil
 }
 
 func (d *Driver) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
-	klog.V(4).Infof("ValidateVolumeCapabilities: called with args %#v", req)
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -259,7 +744,19 @@ func (d *Driver) ValidateVolumeCapabilities(ctx context.Context, req *csi.Valida
 		return nil, status.Error(codes.InvalidArgument, "Volume capabilities not provided")
 	}
 
-	if _, err := d.cloud.DescribeFileSystem(ctx, volumeID); err != nil {
+	fileSystemId, accountId := splitAccountId(volumeID)
+	if strings.HasPrefix(volumeID, sharedVolumeIdPrefix) {
+		shared, err := parseSharedVolumeId(volumeID)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, "Volume not found")
+		}
+		fileSystemId, accountId = shared.fileSystemId, shared.accountId
+	}
+	c, err := d.cloudForAccount(ctx, accountId, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.DescribeFileSystem(ctx, fileSystemId); err != nil {
 		if err == cloud.ErrNotFound {
 			return nil, status.Error(codes.NotFound, "Volume not found")
 		}
@@ -302,45 +799,324 @@ func (d *Driver) isValidVolumeCapabilities(volCaps []*csi.VolumeCapability) bool
 }
 
 func (d *Driver) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	snapshotName := req.GetName()
+	if len(snapshotName) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Snapshot name not provided")
+	}
+
+	volumeID := req.GetSourceVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Snapshot volume source ID not provided")
+	}
+
+	// Backups are taken of a whole filesystem, so they can't be scoped to a
+	// single volume carved out of a shared filesystem.
+	if strings.HasPrefix(volumeID, sharedVolumeIdPrefix) {
+		return nil, status.Errorf(codes.InvalidArgument, "Snapshot of shared volume %q is not supported", volumeID)
+	}
+
+	fileSystemId, accountId := splitAccountId(volumeID)
+	c, err := d.cloudForAccount(ctx, accountId, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := c.DescribeFileSystem(ctx, fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Source volume %q not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get source volume %q: %v", volumeID, err)
+	}
+	backupOptions := &cloud.BackupOptions{
+		FileSystemId: fileSystemId,
+	}
+	// FSx doesn't copy the tags of the filesystem to backups created with
+	// tags of their own, so they are copied here
+	if fs.CopyTagsToBackups {
+		backupOptions.Tags, err = backupTags(fs.Tags)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Could not copy the tags of volume %q: %v", volumeID, err)
+		}
+	}
+	backup, err := c.CreateBackup(ctx, snapshotName, backupOptions)
+	if err != nil {
+		switch err {
+		case cloud.ErrNotFound:
+			return nil, status.Errorf(codes.NotFound, "Source volume %q not found", volumeID)
+		case cloud.ErrBackupExistsDiffFs:
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		default:
+			return nil, status.Errorf(codes.Internal, "Could not create snapshot %q: %v", snapshotName, err)
+		}
+	}
+
+	// Backups can take a long time depending on the data changed since the
+	// last one, so the snapshot is returned as not ready to use instead of
+	// waiting for it. The sidecar calls again until it is ready.
+	if backup.Lifecycle == fsx.BackupLifecycleFailed {
+		return nil, status.Errorf(codes.Internal, "Snapshot %q failed", snapshotName)
+	}
+
+	snapshot, err := newSnapshot(backup, accountId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not convert snapshot %q: %v", snapshotName, err)
+	}
+	return &csi.CreateSnapshotResponse{Snapshot: snapshot}, nil
 }
 
 func (d *Driver) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	snapshotID := req.GetSnapshotId()
+	if len(snapshotID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Snapshot ID not provided")
+	}
+
+	backupId, accountId := splitAccountId(snapshotID)
+	c, err := d.cloudForAccount(ctx, accountId, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.DeleteBackup(ctx, backupId); err != nil {
+		if err == cloud.ErrNotFound {
+			klog.V(4).Infof("DeleteSnapshot: snapshot not found, returning with success")
+			return &csi.DeleteSnapshotResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "Could not delete snapshot ID %q: %v", snapshotID, err)
+	}
+	return &csi.DeleteSnapshotResponse{}, nil
 }
 
 func (d *Driver) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	var backups []*cloud.Backup
+
+	snapshotID := req.GetSnapshotId()
+	sourceVolumeID := req.GetSourceVolumeId()
+	backupId, accountId := splitAccountId(snapshotID)
+	fileSystemId, volumeAccountId := splitAccountId(sourceVolumeID)
+	if len(snapshotID) == 0 {
+		accountId = volumeAccountId
+	} else if len(sourceVolumeID) != 0 && volumeAccountId != accountId {
+		return &csi.ListSnapshotsResponse{}, nil
+	}
+	c, err := d.cloudForAccount(ctx, accountId, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snapshotID) != 0 {
+		backup, err := c.DescribeBackup(ctx, backupId)
+		if err != nil {
+			if err == cloud.ErrNotFound {
+				klog.V(4).Infof("ListSnapshots: snapshot not found, returning with success")
+				return &csi.ListSnapshotsResponse{}, nil
+			}
+			return nil, status.Errorf(codes.Internal, "Could not get snapshot ID %q: %v", snapshotID, err)
+		}
+		if len(sourceVolumeID) != 0 && backup.FileSystemId != fileSystemId {
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+		backups = append(backups, backup)
+	} else if strings.HasPrefix(sourceVolumeID, sharedVolumeIdPrefix) {
+		return &csi.ListSnapshotsResponse{}, nil
+	} else {
+		backups, err = c.DescribeBackups(ctx, fileSystemId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not list snapshots: %v", err)
+		}
+	}
+
+	// Sort the backups so that the tokens handed out stay valid across calls
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].BackupId < backups[j].BackupId
+	})
+
+	start, end, nextToken, err := getPageBounds(len(backups), req.GetMaxEntries(), req.GetStartingToken())
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*csi.ListSnapshotsResponse_Entry
+	for _, backup := range backups[start:end] {
+		snapshot, err := newSnapshot(backup, accountId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not convert snapshot ID %q: %v", backup.BackupId, err)
+		}
+		entries = append(entries, &csi.ListSnapshotsResponse_Entry{Snapshot: snapshot})
+	}
+
+	return &csi.ListSnapshotsResponse{
+		Entries:   entries,
+		NextToken: nextToken,
+	}, nil
 }
 
 func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	capRange := req.GetCapacityRange()
+	if capRange == nil {
+		return nil, status.Error(codes.InvalidArgument, "Capacity range not provided")
+	}
+
+	// A shared volume is a directory of a filesystem that isn't managed by
+	// this driver, so there is no capacity to grow.
+	if strings.HasPrefix(volumeID, sharedVolumeIdPrefix) {
+		return nil, status.Errorf(codes.InvalidArgument, "Expansion of shared volume %q is not supported", volumeID)
+	}
+
+	fileSystemId, accountId := splitAccountId(volumeID)
+	c, err := d.cloudForAccount(ctx, accountId, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := c.DescribeFileSystem(ctx, fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
+	}
+
+	// Storage capacity of SCRATCH_1 filesystems can't be increased.
+	if fs.DeploymentType == fsx.LustreDeploymentTypeScratch1 || fs.DeploymentType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Expansion of volume %q of deployment type %s is not supported", volumeID, fsx.LustreDeploymentTypeScratch1)
+	}
+
+	newCapacityGiB := util.RoundUpVolumeSize(capRange.GetRequiredBytes(), fs.DeploymentType, fs.StorageType, fs.PerUnitStorageThroughput)
+	if limitBytes := capRange.GetLimitBytes(); limitBytes > 0 && util.GiBToBytes(newCapacityGiB) > limitBytes {
+		return nil, status.Errorf(codes.OutOfRange, "Requested capacity %d GiB exceeds limit of %d bytes", newCapacityGiB, limitBytes)
+	}
+
+	if newCapacityGiB > fs.CapacityGiB {
+		newCapacityGiB, err = c.ResizeFileSystem(ctx, fileSystemId, newCapacityGiB)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not resize volume %q: %v", volumeID, err)
+		}
+	} else {
+		// The filesystem already has the requested capacity, but a previous
+		// resize to it may still be in progress.
+		newCapacityGiB = fs.CapacityGiB
+	}
+
+	err = c.WaitForFileSystemResize(ctx, fileSystemId, newCapacityGiB)
+	if err != nil {
+		if ctx.Err() != nil {
+			// The resize continues in FSx, the retried request waits for it
+			return nil, status.Errorf(codes.DeadlineExceeded, "Volume %q is still being resized: %v", volumeID, err)
+		}
+		return nil, status.Errorf(codes.Internal, "Volume %q is not resized: %v", volumeID, err)
+	}
+
+	// Lustre clients see the new capacity online, there is nothing to do on the node
+	return &csi.ControllerExpandVolumeResponse{
+		CapacityBytes:         util.GiBToBytes(newCapacityGiB),
+		NodeExpansionRequired: false,
+	}, nil
 }
 
-func newCreateVolumeResponseWithSubPath(subPath string, fs *cloud.FileSystem) *csi.CreateVolumeResponse {
+// newCreateVolumeResponseWithSubPath returns the shared volume, whose capacity
+// is its quota, or the capacity of its filesystem if quotaBytes is 0.
+func newCreateVolumeResponseWithSubPath(v *sharedVolume, fs *cloud.FileSystem, quotaBytes int64) *csi.CreateVolumeResponse {
+	capacityBytes := quotaBytes
+	if capacityBytes == 0 {
+		capacityBytes = util.GiBToBytes(fs.CapacityGiB)
+	}
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
-			VolumeId:      fmt.Sprintf("%s/%s/%s", sharedVolumeIdPrefix, fs.FileSystemId, subPath),
-			CapacityBytes: util.GiBToBytes(fs.CapacityGiB),
+			VolumeId:      v.volumeId(),
+			CapacityBytes: capacityBytes,
 			VolumeContext: map[string]string{
 				volumeContextDnsName:      fs.DnsName,
 				volumeContextMountName:    fs.MountName,
-				volumeContextSubPath:      subPath,
+				volumeContextSubPath:      v.subPath,
 				volumeContextFileSystemId: fs.FileSystemId,
 			},
 		},
 	}
 }
 
-func newCreateVolumeResponse(fs *cloud.FileSystem) *csi.CreateVolumeResponse {
+// newCreateVolumeResponse returns the volume of the filesystem, whose ID
+// includes the account of the filesystem if it is managed with the
+// credentials of secrets.
+func newCreateVolumeResponse(fs *cloud.FileSystem, accountId string, volumeSource *csi.VolumeContentSource, accessibleTopology []*csi.Topology) *csi.CreateVolumeResponse {
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
-			VolumeId:      fs.FileSystemId,
+			VolumeId:      joinAccountId(fs.FileSystemId, accountId),
 			CapacityBytes: util.GiBToBytes(fs.CapacityGiB),
 			VolumeContext: map[string]string{
 				volumeContextDnsName:   fs.DnsName,
 				volumeContextMountName: fs.MountName,
 			},
+			ContentSource:      volumeSource,
+			AccessibleTopology: accessibleTopology,
 		},
 	}
 }
+
+// newVolumeCondition reports a filesystem as abnormal unless it is available,
+// or being updated which doesn't interrupt access to it.
+func newVolumeCondition(fs *cloud.FileSystem) *csi.VolumeCondition {
+	switch fs.Lifecycle {
+	case fsx.FileSystemLifecycleAvailable, fsx.FileSystemLifecycleUpdating:
+		return &csi.VolumeCondition{
+			Abnormal: false,
+			Message:  fmt.Sprintf("Filesystem %s is %s", fs.FileSystemId, fs.Lifecycle),
+		}
+	default:
+		message := fmt.Sprintf("Filesystem %s is %s", fs.FileSystemId, fs.Lifecycle)
+		if fs.FailureDetails != "" {
+			message = fmt.Sprintf("%s: %s", message, fs.FailureDetails)
+		}
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  message,
+		}
+	}
+}
+
+func newSnapshot(backup *cloud.Backup, accountId string) (*csi.Snapshot, error) {
+	creationTime, err := ptypes.TimestampProto(backup.CreationTime)
+	if err != nil {
+		return nil, err
+	}
+	return &csi.Snapshot{
+		SnapshotId:     joinAccountId(backup.BackupId, accountId),
+		SourceVolumeId: joinAccountId(backup.FileSystemId, accountId),
+		SizeBytes:      util.GiBToBytes(backup.CapacityGiB),
+		CreationTime:   creationTime,
+		ReadyToUse:     backup.Lifecycle == fsx.BackupLifecycleAvailable,
+	}, nil
+}
+
+// getPageBounds returns the range of entries to return for a list request,
+// along with the token of the next page. Tokens are the index of the first
+// entry of a page, formatted as a string.
+func getPageBounds(numEntries int, maxEntries int32, startingToken string) (int, int, string, error) {
+	if maxEntries < 0 {
+		return 0, 0, "", status.Error(codes.InvalidArgument, "max_entries must not be negative")
+	}
+
+	start := 0
+	if len(startingToken) != 0 {
+		n, err := strconv.Atoi(startingToken)
+		if err != nil || n < 0 || n > numEntries {
+			return 0, 0, "", status.Errorf(codes.Aborted, "Invalid starting token %q", startingToken)
+		}
+		start = n
+	}
+
+	end := numEntries
+	if maxEntries > 0 && start+int(maxEntries) < numEntries {
+		end = start + int(maxEntries)
+	}
+
+	nextToken := ""
+	if end < numEntries {
+		nextToken = strconv.Itoa(end)
+	}
+	return start, end, nextToken, nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The keys of the AWS credentials in the secrets of requests, which are set
+// by the provisioner and deleter secrets of the storageclass
+const (
+	secretsAccessKeyId     = "awsAccessKeyId"
+	secretsSecretAccessKey = "awsSecretAccessKey"
+	secretsSessionToken    = "awsSessionToken"
+	secretsRoleArn         = "roleArn"
+	secretsExternalId      = "externalId"
+)
+
+// accountSeparator separates the ID of a filesystem or backup managed with
+// the credentials of secrets from the ID of the account it is in, in the IDs
+// of volumes and snapshots, e.g. "fs-0123456789abcdef0@123456789012"
+const accountSeparator = "@"
+
+// splitAccountId splits an ID into the ID of the resource and the ID of its
+// account, which is empty for resources of the controller's credentials
+func splitAccountId(id string) (string, string) {
+	i := strings.LastIndex(id, accountSeparator)
+	if i < 0 || !isAccountId(id[i+1:]) {
+		return id, ""
+	}
+	return id[:i], id[i+1:]
+}
+
+// isAccountId returns whether s is an AWS account ID, which is 12 digits
+func isAccountId(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func joinAccountId(id, accountId string) string {
+	if accountId == "" {
+		return id
+	}
+	return id + accountSeparator + accountId
+}
+
+// parseCredentials returns the credentials in secrets, or nil if there are
+// none
+func parseCredentials(secrets map[string]string) (*cloud.Credentials, error) {
+	c := &cloud.Credentials{
+		AccessKeyId:     secrets[secretsAccessKeyId],
+		SecretAccessKey: secrets[secretsSecretAccessKey],
+		SessionToken:    secrets[secretsSessionToken],
+		RoleArn:         secrets[secretsRoleArn],
+		ExternalId:      secrets[secretsExternalId],
+	}
+	if *c == (cloud.Credentials{}) {
+		return nil, nil
+	}
+	if (c.AccessKeyId == "") != (c.SecretAccessKey == "") {
+		return nil, fmt.Errorf("%s and %s must be provided together", secretsAccessKeyId, secretsSecretAccessKey)
+	}
+	if c.SessionToken != "" && c.AccessKeyId == "" {
+		return nil, fmt.Errorf("%s requires %s and %s", secretsSessionToken, secretsAccessKeyId, secretsSecretAccessKey)
+	}
+	if c.ExternalId != "" && c.RoleArn == "" {
+		return nil, fmt.Errorf("%s requires %s", secretsExternalId, secretsRoleArn)
+	}
+	if c.RoleArn != "" {
+		if _, err := arn.Parse(c.RoleArn); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", secretsRoleArn, err)
+		}
+	}
+	return c, nil
+}
+
+// maxCachedClouds is the number of clouds of credentials of requests that
+// are cached, beyond which the oldest is evicted
+const maxCachedClouds = 100
+
+// cachedCloud is the cloud of credentials of requests, along with the account
+// of the credentials once looked up.
+type cachedCloud struct {
+	mux         sync.Mutex
+	credentials cloud.Credentials
+	cloud       cloud.Cloud
+	accountId   string
+}
+
+// AccountId returns the account of the credentials of the cloud, looked up
+// on first use.
+func (cc *cachedCloud) AccountId(ctx context.Context) (string, error) {
+	cc.mux.Lock()
+	defer cc.mux.Unlock()
+	if cc.accountId == "" {
+		accountId, err := cc.cloud.GetAccountId(ctx)
+		if err != nil {
+			return "", err
+		}
+		cc.accountId = accountId
+	}
+	return cc.accountId, nil
+}
+
+// cloudCache caches the clouds of the credentials of requests, so that their
+// clients and assumed roles are reused. Clouds are keyed by the role ARN of
+// the credentials, or their access key ID if they have no role, so that
+// rotated credentials replace the cloud of the previous ones rather than
+// piling up, and the secrets aren't kept as keys. Its zero value is ready to
+// use.
+type cloudCache struct {
+	mux    sync.Mutex
+	clouds map[string]*cachedCloud
+	// keys are the keys of clouds from the oldest to the newest
+	keys []string
+}
+
+func credentialsKey(credentials cloud.Credentials) string {
+	if credentials.RoleArn != "" {
+		return credentials.RoleArn
+	}
+	return credentials.AccessKeyId
+}
+
+// Get returns the cloud of the credentials, creating it with newCloud if it
+// isn't cached, or is cached for other credentials of the same key.
+func (c *cloudCache) Get(credentials cloud.Credentials, newCloud func() cloud.Cloud) *cachedCloud {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	key := credentialsKey(credentials)
+	cc, ok := c.clouds[key]
+	if ok && cc.credentials == credentials {
+		return cc
+	}
+	if c.clouds == nil {
+		c.clouds = map[string]*cachedCloud{}
+	}
+	if !ok {
+		if len(c.keys) == maxCachedClouds {
+			delete(c.clouds, c.keys[0])
+			c.keys = c.keys[1:]
+		}
+		c.keys = append(c.keys, key)
+	}
+	cc = &cachedCloud{
+		credentials: credentials,
+		cloud:       newCloud(),
+	}
+	c.clouds[key] = cc
+	return cc
+}
+
+// cloudForCredentials returns the cached cloud of the credentials.
+func (d *Driver) cloudForCredentials(credentials *cloud.Credentials) *cachedCloud {
+	return d.clouds.Get(*credentials, func() cloud.Cloud {
+		options := append([]func(*cloud.CloudOptions){}, d.cloudOptions...)
+		options = append(options, cloud.WithCredentials(credentials))
+		return d.newCloud(d.region, options...)
+	})
+}
+
+// cloudForSecrets returns the cloud of the credentials in secrets, and
+// whether there are credentials. Without credentials, it returns the cloud
+// of the controller.
+func (d *Driver) cloudForSecrets(secrets map[string]string) (cloud.Cloud, bool, error) {
+	credentials, err := parseCredentials(secrets)
+	if err != nil {
+		return nil, false, status.Errorf(codes.InvalidArgument, "Invalid credentials in secrets: %v", err)
+	}
+	if credentials == nil {
+		return d.cloud, false, nil
+	}
+	return d.cloudForCredentials(credentials).cloud, true, nil
+}
+
+// cloudForAccount returns the cloud to manage a resource of the account
+// with: the cloud of the credentials in secrets, or the cloud of the
+// controller for resources without an account. A resource of an account is
+// only managed with credentials of the account in the secrets of the
+// request, checked against the account of the role, or of the static keys
+// through STS, so that a request can't reach the resources of an account
+// with credentials that were provided by other requests, and a resource
+// isn't reported as gone because it was looked up in the wrong account.
+func (d *Driver) cloudForAccount(ctx context.Context, accountId string, secrets map[string]string) (cloud.Cloud, error) {
+	credentials, err := parseCredentials(secrets)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid credentials in secrets: %v", err)
+	}
+	if credentials == nil {
+		if accountId != "" {
+			return nil, status.Errorf(codes.FailedPrecondition, "Credentials of account %s are not provided in secrets", accountId)
+		}
+		return d.cloud, nil
+	}
+	cc := d.cloudForCredentials(credentials)
+	if accountId == "" {
+		return cc.cloud, nil
+	}
+	if credentials.RoleArn != "" {
+		if role, _ := arn.Parse(credentials.RoleArn); role.AccountID != accountId {
+			return nil, status.Errorf(codes.FailedPrecondition, "Role %s is not in account %s", credentials.RoleArn, accountId)
+		}
+		return cc.cloud, nil
+	}
+	credentialsAccountId, err := cc.AccountId(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get the account of the credentials in secrets: %v", err)
+	}
+	if credentialsAccountId != accountId {
+		return nil, status.Errorf(codes.FailedPrecondition, "Credentials in secrets are not of account %s", accountId)
+	}
+	return cc.cloud, nil
+}
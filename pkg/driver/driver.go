@@ -18,6 +18,7 @@ package driver
 
 import (
 	"context"
+	"fmt"
 	"net"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
@@ -29,6 +30,10 @@ import (
 
 const (
 	DriverName = "fsx.csi.aws.com"
+
+	// TopologyKey is the key of the availability zone segment in the topology
+	// of nodes and volumes
+	TopologyKey = "topology." + DriverName + "/zone"
 )
 
 var (
@@ -42,33 +47,173 @@ var (
 	}
 )
 
+// Mode is the operating mode of the driver, it determines which CSI
+// services are served.
+type Mode string
+
+const (
+	// ControllerMode serves the identity and controller services
+	ControllerMode Mode = "controller"
+	// NodeMode serves the identity and node services
+	NodeMode Mode = "node"
+	// AllMode serves the identity, controller and node services
+	AllMode Mode = "all"
+)
+
 type Driver struct {
-	endpoint string
-	srv      *grpc.Server
+	endpoint       string
+	mode           Mode
+	metricsAddress string
+	srv            *grpc.Server
+	sanitizer      *sanitizer
+	// extraTags are set on every filesystem the controller creates
+	extraTags map[string]string
+	// clusterId is recorded on the filesystems the controller creates,
+	// which are the only ones it deletes if set
+	clusterId string
 
 	cloud cloud.Cloud
+	// clouds of the credentials in the secrets of requests are created in
+	// region with cloudOptions by newCloud, and cached in clouds
+	region       string
+	cloudOptions []func(*cloud.CloudOptions)
+	newCloud     func(region string, options ...func(*cloud.CloudOptions)) cloud.Cloud
+	clouds       cloudCache
+
+	nodeID           string
+	availabilityZone string
+	mounter          Mounter
+	executor         Executor
 
-	nodeID  string
-	mounter Mounter
+	inFlight inFlight
 }
 
-func NewDriver(endpoint string) *Driver {
-	metadata, err := cloud.NewMetadata()
-	if err != nil {
-		klog.Fatalln(err)
+// DriverOptions holds the options of the driver set by NewDriver's options
+type DriverOptions struct {
+	endpoint       string
+	mode           Mode
+	metricsAddress string
+	sensitiveKeys  []string
+	extraTags      map[string]string
+	clusterId      string
+	cloudOptions   []func(*cloud.CloudOptions)
+}
+
+// WithEndpoint sets the CSI endpoint the driver listens on
+func WithEndpoint(endpoint string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithMode sets the operating mode of the driver
+func WithMode(mode Mode) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.mode = mode
 	}
+}
 
-	region := metadata.GetRegion()
-	cloud := cloud.NewCloud(region)
+// WithMetricsAddress sets the address Prometheus metrics are served on. An
+// empty address disables the metrics.
+func WithMetricsAddress(address string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.metricsAddress = address
+	}
+}
 
-	return &Driver{
-		endpoint: endpoint,
-		nodeID:   metadata.GetInstanceID(),
-		cloud:    cloud,
-		mounter:  newNodeMounter(),
+// WithSensitiveKeys sets the keys of parameters and volume context whose
+// values are stripped from logged requests, besides the secrets
+func WithSensitiveKeys(keys []string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.sensitiveKeys = keys
 	}
 }
 
+// WithExtraTags sets the tags set on every filesystem the controller creates
+func WithExtraTags(tags map[string]string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.extraTags = tags
+	}
+}
+
+// WithClusterId sets the ID of the cluster the controller creates
+// filesystems for
+func WithClusterId(clusterId string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.clusterId = clusterId
+	}
+}
+
+// WithCloudOptions sets the options of the cloud used by the controller
+func WithCloudOptions(options ...func(*cloud.CloudOptions)) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.cloudOptions = append(o.cloudOptions, options...)
+	}
+}
+
+func NewDriver(options ...func(*DriverOptions)) (*Driver, error) {
+	driverOptions := DriverOptions{
+		endpoint:      "unix://tmp/csi.sock",
+		mode:          AllMode,
+		sensitiveKeys: DefaultSensitiveKeys,
+	}
+	for _, option := range options {
+		option(&driverOptions)
+	}
+	if err := validateClusterId(driverOptions.clusterId); err != nil {
+		return nil, err
+	}
+
+	driver := &Driver{
+		endpoint:       driverOptions.endpoint,
+		mode:           driverOptions.mode,
+		metricsAddress: driverOptions.metricsAddress,
+		sanitizer:      newSanitizer(driverOptions.sensitiveKeys),
+		extraTags:      driverOptions.extraTags,
+		clusterId:      driverOptions.clusterId,
+		cloudOptions:   driverOptions.cloudOptions,
+		newCloud:       cloud.NewCloud,
+	}
+
+	switch driverOptions.mode {
+	case ControllerMode:
+		// The controller only needs metadata to discover subnets in its own
+		// VPC, so it can run off EC2 with the region set in AWS_REGION.
+		region := ""
+		metadata, err := cloud.NewMetadata()
+		if err != nil {
+			klog.Warningf("Could not get EC2 metadata, subnet discovery is disabled: %v", err)
+		} else {
+			region = metadata.GetRegion()
+			driver.nodeID = metadata.GetInstanceID()
+			driver.availabilityZone = metadata.GetAvailabilityZone()
+		}
+		driver.region = region
+		driver.cloud = cloud.NewCloud(region, driverOptions.cloudOptions...)
+		// subpaths of shared volumes are managed through a mount of their
+		// filesystem
+		driver.mounter = newNodeMounter()
+		driver.executor = newNodeExecutor()
+	case NodeMode, AllMode:
+		metadata, err := cloud.NewMetadata()
+		if err != nil {
+			return nil, err
+		}
+		driver.nodeID = metadata.GetInstanceID()
+		driver.availabilityZone = metadata.GetAvailabilityZone()
+		driver.mounter = newNodeMounter()
+		driver.executor = newNodeExecutor()
+		if driverOptions.mode == AllMode {
+			driver.region = metadata.GetRegion()
+			driver.cloud = cloud.NewCloud(driver.region, driverOptions.cloudOptions...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", driverOptions.mode)
+	}
+
+	return driver, nil
+}
+
 func (d *Driver) Run() error {
 	scheme, addr, err := util.ParseEndpoint(d.endpoint)
 	if err != nil {
@@ -88,15 +233,30 @@ func (d *Driver) Run() error {
 		return resp, err
 	}
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(logErr),
+		grpc.UnaryInterceptor(chainUnaryInterceptors(recordMetrics, d.sanitizer.logRequest, logErr)),
 	}
 	d.srv = grpc.NewServer(opts...)
 
 	csi.RegisterIdentityServer(d.srv, d)
-	csi.RegisterControllerServer(d.srv, d)
-	csi.RegisterNodeServer(d.srv, d)
+	switch d.mode {
+	case ControllerMode:
+		csi.RegisterControllerServer(d.srv, d)
+	case NodeMode:
+		csi.RegisterNodeServer(d.srv, d)
+	case AllMode:
+		csi.RegisterControllerServer(d.srv, d)
+		csi.RegisterNodeServer(d.srv, d)
+	default:
+		return fmt.Errorf("unknown mode: %s", d.mode)
+	}
+
+	if d.metricsAddress != "" {
+		if err := serveMetrics(d.metricsAddress); err != nil {
+			return err
+		}
+	}
 
-	klog.Infof("Listening for connections on address: %#v", listener.Addr())
+	klog.Infof("Listening for connections on address: %#v in %s mode", listener.Addr(), d.mode)
 	return d.srv.Serve(listener)
 }
 
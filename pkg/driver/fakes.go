@@ -29,13 +29,16 @@ func NewFakeMounter() Mounter {
 	}
 }
 
-// NewFakeDriver creates a new mock driver used for testing
+// NewFakeDriver creates a new mock driver used for testing, whose cloud is
+// backed by fakes of the FSx and EC2 APIs
 func NewFakeDriver(endpoint string) *Driver {
-	cloud := cloud.NewFakeCloudProvider()
+	metadata := cloud.NewFakeMetadata()
 	return &Driver{
-		endpoint: endpoint,
-		nodeID:   cloud.GetMetadata().GetInstanceID(),
-		cloud:    cloud,
-		mounter:  NewFakeMounter(),
+		endpoint:         endpoint,
+		mode:             AllMode,
+		nodeID:           metadata.GetInstanceID(),
+		availabilityZone: metadata.GetAvailabilityZone(),
+		cloud:            cloud.NewFakeCloud(cloud.NewFakeFSx(), cloud.NewFakeEC2(metadata.GetInstanceID(), metadata.GetAvailabilityZone())),
+		mounter:          NewFakeMounter(),
 	}
 }
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestGetPluginCapabilities(t *testing.T) {
+	testCases := []struct {
+		name             string
+		mode             Mode
+		expectController bool
+	}{
+		{
+			name:             "success: controller mode",
+			mode:             ControllerMode,
+			expectController: true,
+		},
+		{
+			name:             "success: node mode",
+			mode:             NodeMode,
+			expectController: false,
+		},
+		{
+			name:             "success: all mode",
+			mode:             AllMode,
+			expectController: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			driver := &Driver{
+				endpoint: "endpoint",
+				mode:     tc.mode,
+			}
+
+			resp, err := driver.GetPluginCapabilities(context.Background(), &csi.GetPluginCapabilitiesRequest{})
+			if err != nil {
+				t.Fatalf("GetPluginCapabilities is failed: %v", err)
+			}
+
+			hasController := false
+			for _, cap := range resp.GetCapabilities() {
+				if cap.GetService().GetType() == csi.PluginCapability_Service_CONTROLLER_SERVICE {
+					hasController = true
+				}
+			}
+			if hasController != tc.expectController {
+				t.Fatalf("CONTROLLER_SERVICE capability mismatches. actual: %v expected: %v", hasController, tc.expectController)
+			}
+		})
+	}
+}
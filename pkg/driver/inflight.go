@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sync"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/proto"
+)
+
+// pendingVolume is a filesystem whose creation was requested for a volume
+// but which wasn't available yet when CreateVolume returned. It keeps the
+// parts of the request that determine the filesystem, but not its secrets.
+type pendingVolume struct {
+	name                string
+	parameters          map[string]string
+	capacityRange       *csi.CapacityRange
+	volumeCapabilities  []*csi.VolumeCapability
+	volumeContentSource *csi.VolumeContentSource
+	fileSystemId        string
+	accessibleTopology  []*csi.Topology
+}
+
+func newPendingVolume(req *csi.CreateVolumeRequest, fileSystemId string, accessibleTopology []*csi.Topology) *pendingVolume {
+	return &pendingVolume{
+		name:                req.GetName(),
+		parameters:          req.GetParameters(),
+		capacityRange:       req.GetCapacityRange(),
+		volumeCapabilities:  req.GetVolumeCapabilities(),
+		volumeContentSource: req.GetVolumeContentSource(),
+		fileSystemId:        fileSystemId,
+		accessibleTopology:  accessibleTopology,
+	}
+}
+
+// matches returns whether the filesystem was created for a request with the
+// same parameters, capacity range, capabilities and content source.
+func (p *pendingVolume) matches(req *csi.CreateVolumeRequest) bool {
+	if len(p.parameters) != len(req.GetParameters()) {
+		return false
+	}
+	for key, value := range req.GetParameters() {
+		if v, ok := p.parameters[key]; !ok || v != value {
+			return false
+		}
+	}
+	if !proto.Equal(p.capacityRange, req.GetCapacityRange()) || !proto.Equal(p.volumeContentSource, req.GetVolumeContentSource()) {
+		return false
+	}
+	if len(p.volumeCapabilities) != len(req.GetVolumeCapabilities()) {
+		return false
+	}
+	for i, volCap := range req.GetVolumeCapabilities() {
+		if !proto.Equal(p.volumeCapabilities[i], volCap) {
+			return false
+		}
+	}
+	return true
+}
+
+// inFlight tracks the CreateVolume requests being served, so that concurrent
+// requests for the same volume name are rejected, and the filesystems still
+// being created, so that retries resume waiting for them. Its zero value is
+// ready to use.
+type inFlight struct {
+	mux      sync.Mutex
+	requests map[string]bool
+	pending  map[string]*pendingVolume
+}
+
+// Insert marks the request for the volume name as being served, and returns
+// false if another request for that name already is.
+func (f *inFlight) Insert(name string) bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.requests[name] {
+		return false
+	}
+	if f.requests == nil {
+		f.requests = map[string]bool{}
+	}
+	f.requests[name] = true
+	return true
+}
+
+// Delete marks the request for the volume name as served.
+func (f *inFlight) Delete(name string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	delete(f.requests, name)
+}
+
+// GetPending returns the filesystem being created for the request, or nil if
+// there is none or it was created for a different request of the same name.
+func (f *inFlight) GetPending(req *csi.CreateVolumeRequest) *pendingVolume {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	p := f.pending[req.GetName()]
+	if p == nil || !p.matches(req) {
+		return nil
+	}
+	return p
+}
+
+// SetPending remembers the filesystem being created for the request.
+func (f *inFlight) SetPending(p *pendingVolume) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.pending == nil {
+		f.pending = map[string]*pendingVolume{}
+	}
+	f.pending[p.name] = p
+}
+
+// DeletePending forgets the filesystem being created for the volume name.
+func (f *inFlight) DeletePending(name string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	delete(f.pending, name)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestInFlight(t *testing.T) {
+	var f inFlight
+
+	if !f.Insert("vol-1") {
+		t.Fatal("Insert of a new name is failed")
+	}
+	if f.Insert("vol-1") {
+		t.Fatal("Insert of a name in flight is not failed")
+	}
+	if !f.Insert("vol-2") {
+		t.Fatal("Insert of another name is failed")
+	}
+	f.Delete("vol-1")
+	if !f.Insert("vol-1") {
+		t.Fatal("Insert of a deleted name is failed")
+	}
+}
+
+func TestInFlightPending(t *testing.T) {
+	var f inFlight
+
+	req := &csi.CreateVolumeRequest{
+		Name:          "vol-1",
+		Parameters:    map[string]string{"subnetId": "subnet-1"},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 1200},
+		Secrets:       map[string]string{"awsSecretAccessKey": "secret-1"},
+	}
+	if f.GetPending(req) != nil {
+		t.Fatal("Pending volume of an unknown request is not nil")
+	}
+
+	f.SetPending(newPendingVolume(req, "fs-1", nil))
+	// the secrets of a retry may have been rotated meanwhile
+	p := f.GetPending(&csi.CreateVolumeRequest{
+		Name:          "vol-1",
+		Parameters:    map[string]string{"subnetId": "subnet-1"},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 1200},
+		Secrets:       map[string]string{"awsSecretAccessKey": "secret-2"},
+	})
+	if p == nil || p.fileSystemId != "fs-1" {
+		t.Fatalf("Pending volume mismatches. actual: %v", p)
+	}
+
+	if f.GetPending(&csi.CreateVolumeRequest{
+		Name:          "vol-1",
+		Parameters:    map[string]string{"subnetId": "subnet-2"},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 1200},
+	}) != nil {
+		t.Fatal("Pending volume of a request with different parameters is not nil")
+	}
+
+	if f.GetPending(&csi.CreateVolumeRequest{
+		Name:          "vol-1",
+		Parameters:    map[string]string{"subnetId": "subnet-1"},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 2400},
+	}) != nil {
+		t.Fatal("Pending volume of a request with a different capacity is not nil")
+	}
+
+	f.DeletePending("vol-1")
+	if f.GetPending(req) != nil {
+		t.Fatal("Deleted pending volume is not nil")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Executor runs commands on the node
+type Executor interface {
+	Run(name string, args ...string) ([]byte, error)
+}
+
+type nodeExecutor struct{}
+
+func newNodeExecutor() Executor {
+	return &nodeExecutor{}
+}
+
+// Run runs the command and returns its combined output
+func (e *nodeExecutor) Run(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// lustreParam is a Lustre client parameter that may be set per volume,
+// either on the llite instance of the mount or on its osc instances.
+type lustreParam struct {
+	key    string
+	device string
+	name   string
+	parse  func(val string) (string, error)
+}
+
+var lustreClientParams = []lustreParam{
+	{
+		key:    volumeContextMaxCachedMB,
+		device: "llite",
+		name:   "max_cached_mb",
+		parse:  parseIntInRange(1, 1<<31-1),
+	},
+	{
+		key:    volumeContextMaxRpcsInFlight,
+		device: "osc",
+		name:   "max_rpcs_in_flight",
+		parse:  parseIntInRange(1, 256),
+	},
+	{
+		key:    volumeContextChecksums,
+		device: "osc",
+		name:   "checksums",
+		parse: func(val string) (string, error) {
+			enabled, err := strconv.ParseBool(val)
+			if err != nil {
+				return "", fmt.Errorf("must be a bool")
+			}
+			if enabled {
+				return "1", nil
+			}
+			return "0", nil
+		},
+	},
+}
+
+func parseIntInRange(min, max int) func(val string) (string, error) {
+	return func(val string) (string, error) {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < min || i > max {
+			return "", fmt.Errorf("must be a number between %d and %d", min, max)
+		}
+		return strconv.Itoa(i), nil
+	}
+}
+
+// parseLustreParams returns the validated values of the Lustre client
+// parameters set in the volume parameters or context, keyed like them.
+func parseLustreParams(values map[string]string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, p := range lustreClientParams {
+		val, ok := values[p.key]
+		if !ok {
+			continue
+		}
+		parsed, err := p.parse(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("%s %s", p.key, err)
+		}
+		params[p.key] = parsed
+	}
+	return params, nil
+}
+
+// setLustreParams sets the Lustre client parameters on the instances of the
+// filesystem mounted at target only, leaving other mounts of the node, even
+// of the same filesystem, untouched.
+func (d *Driver) setLustreParams(target string, params map[string]string) error {
+	// lfs getname prints "<fsname>-<instance id> <mount point>"
+	out, err := d.executor.Run("lfs", "getname", target)
+	if err != nil {
+		return fmt.Errorf("could not get the Lustre instance of %s: %v: %s", target, err, out)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return fmt.Errorf("could not get the Lustre instance of %s: unexpected output %q", target, out)
+	}
+	instance := fields[0]
+	sep := strings.LastIndex(instance, "-")
+	if sep <= 0 {
+		return fmt.Errorf("could not get the Lustre instance of %s: unexpected output %q", target, out)
+	}
+	fsname, instanceId := instance[:sep], instance[sep+1:]
+
+	args := []string{"set_param"}
+	for _, p := range lustreClientParams {
+		val, ok := params[p.key]
+		if !ok {
+			continue
+		}
+		// the osc instances of a mount are named
+		// "<fsname>-<target>-osc-<instance id>"
+		device := instance
+		if p.device == "osc" {
+			device = fmt.Sprintf("%s-*-osc-%s", fsname, instanceId)
+		}
+		args = append(args, fmt.Sprintf("%s.%s.%s=%s", p.device, device, p.name, val))
+	}
+	if out, err := d.executor.Run("lctl", args...); err != nil {
+		return fmt.Errorf("could not set Lustre parameters of %s: %v: %s", target, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+const (
+	metricsNamespace = "fsx_csi"
+	metricsPath      = "/metrics"
+)
+
+var (
+	rpcRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "rpc",
+			Name:      "requests_total",
+			Help:      "Number of CSI RPCs handled, by method and gRPC code.",
+		},
+		[]string{"method", "code"},
+	)
+	rpcDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "rpc",
+			Name:      "duration_seconds",
+			Help:      "Latency of CSI RPCs, by method and gRPC code.",
+			Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{"method", "code"},
+	)
+)
+
+// recordMetrics is a gRPC interceptor that records the count and latency of
+// RPCs by method and code
+func recordMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	method := path.Base(info.FullMethod)
+	code := status.Code(err).String()
+	rpcRequests.WithLabelValues(method, code).Inc()
+	rpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// chainUnaryInterceptors returns an interceptor that calls interceptors in
+// order, the first one being the outermost
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
+// newMetricsRegistry returns a registry of the metrics of the driver, of the
+// AWS API requests of its cloud and of the process
+func newMetricsRegistry() (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	for _, c := range []prometheus.Collector{
+		rpcRequests,
+		rpcDuration,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	} {
+		if err := registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := cloud.RegisterMetrics(registry); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// serveMetrics serves the metrics on address in the background
+func serveMetrics(address string) error {
+	registry, err := newMetricsRegistry()
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	go func() {
+		klog.Infof("Serving metrics on address: %s%s", address, metricsPath)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			klog.Errorf("Failed to serve metrics: %v", err)
+		}
+	}()
+	return nil
+}
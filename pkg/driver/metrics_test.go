@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/CreateVolume"}
+	okCount := testutil.ToFloat64(rpcRequests.WithLabelValues("CreateVolume", "OK"))
+	abortedCount := testutil.ToFloat64(rpcRequests.WithLabelValues("CreateVolume", "Aborted"))
+
+	_, err := recordMetrics(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = recordMetrics(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Aborted, "aborted")
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected error code %v, got %v", codes.Aborted, err)
+	}
+
+	if count := testutil.ToFloat64(rpcRequests.WithLabelValues("CreateVolume", "OK")); count != okCount+1 {
+		t.Fatalf("Expected %v OK requests, got %v", okCount+1, count)
+	}
+	if count := testutil.ToFloat64(rpcRequests.WithLabelValues("CreateVolume", "Aborted")); count != abortedCount+1 {
+		t.Fatalf("Expected %v Aborted requests, got %v", abortedCount+1, count)
+	}
+}
+
+func TestChainUnaryInterceptors(t *testing.T) {
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	chained := chainUnaryInterceptors(interceptor("first"), interceptor("second"))
+
+	resp, err := chained(context.Background(), "request", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp != "request" {
+		t.Fatalf("Expected response %q, got %v", "request", resp)
+	}
+	expCalls := []string{"first", "second", "handler"}
+	if !reflect.DeepEqual(calls, expCalls) {
+		t.Fatalf("Expected calls %v, got %v", expCalls, calls)
+	}
+}
+
+func TestNewMetricsRegistry(t *testing.T) {
+	if _, err := newMetricsRegistry(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the collectors are shared, so they can be registered with several
+	// registries
+	if _, err := newMetricsRegistry(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -34,6 +34,36 @@ func (m *MockCloud) EXPECT() *MockCloudMockRecorder {
 	return m.recorder
 }
 
+// CreateBackup mocks base method
+func (m *MockCloud) CreateBackup(arg0 context.Context, arg1 string, arg2 *cloud.BackupOptions) (*cloud.Backup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBackup", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*cloud.Backup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBackup indicates an expected call of CreateBackup
+func (mr *MockCloudMockRecorder) CreateBackup(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBackup", reflect.TypeOf((*MockCloud)(nil).CreateBackup), arg0, arg1, arg2)
+}
+
+// CreateExportTask mocks base method
+func (m *MockCloud) CreateExportTask(arg0 context.Context, arg1 string) (*cloud.DataRepositoryTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExportTask", arg0, arg1)
+	ret0, _ := ret[0].(*cloud.DataRepositoryTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateExportTask indicates an expected call of CreateExportTask
+func (mr *MockCloudMockRecorder) CreateExportTask(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExportTask", reflect.TypeOf((*MockCloud)(nil).CreateExportTask), arg0, arg1)
+}
+
 // CreateFileSystem mocks base method
 func (m *MockCloud) CreateFileSystem(arg0 context.Context, arg1 string, arg2 *cloud.FileSystemOptions) (*cloud.FileSystem, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +79,20 @@ func (mr *MockCloudMockRecorder) CreateFileSystem(arg0, arg1, arg2 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFileSystem", reflect.TypeOf((*MockCloud)(nil).CreateFileSystem), arg0, arg1, arg2)
 }
 
+// DeleteBackup mocks base method
+func (m *MockCloud) DeleteBackup(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBackup", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBackup indicates an expected call of DeleteBackup
+func (mr *MockCloudMockRecorder) DeleteBackup(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBackup", reflect.TypeOf((*MockCloud)(nil).DeleteBackup), arg0, arg1)
+}
+
 // DeleteFileSystem mocks base method
 func (m *MockCloud) DeleteFileSystem(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -63,6 +107,36 @@ func (mr *MockCloudMockRecorder) DeleteFileSystem(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFileSystem", reflect.TypeOf((*MockCloud)(nil).DeleteFileSystem), arg0, arg1)
 }
 
+// DescribeBackup mocks base method
+func (m *MockCloud) DescribeBackup(arg0 context.Context, arg1 string) (*cloud.Backup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeBackup", arg0, arg1)
+	ret0, _ := ret[0].(*cloud.Backup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeBackup indicates an expected call of DescribeBackup
+func (mr *MockCloudMockRecorder) DescribeBackup(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeBackup", reflect.TypeOf((*MockCloud)(nil).DescribeBackup), arg0, arg1)
+}
+
+// DescribeBackups mocks base method
+func (m *MockCloud) DescribeBackups(arg0 context.Context, arg1 string) ([]*cloud.Backup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeBackups", arg0, arg1)
+	ret0, _ := ret[0].([]*cloud.Backup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeBackups indicates an expected call of DescribeBackups
+func (mr *MockCloudMockRecorder) DescribeBackups(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeBackups", reflect.TypeOf((*MockCloud)(nil).DescribeBackups), arg0, arg1)
+}
+
 // DescribeFileSystem mocks base method
 func (m *MockCloud) DescribeFileSystem(arg0 context.Context, arg1 string) (*cloud.FileSystem, error) {
 	m.ctrl.T.Helper()
@@ -78,6 +152,125 @@ func (mr *MockCloudMockRecorder) DescribeFileSystem(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeFileSystem", reflect.TypeOf((*MockCloud)(nil).DescribeFileSystem), arg0, arg1)
 }
 
+// DescribeFileSystems mocks base method
+func (m *MockCloud) DescribeFileSystems(arg0 context.Context) ([]*cloud.FileSystem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeFileSystems", arg0)
+	ret0, _ := ret[0].([]*cloud.FileSystem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeFileSystems indicates an expected call of DescribeFileSystems
+func (mr *MockCloudMockRecorder) DescribeFileSystems(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeFileSystems", reflect.TypeOf((*MockCloud)(nil).DescribeFileSystems), arg0)
+}
+
+// DescribeSubnets mocks base method
+func (m *MockCloud) DescribeSubnets(arg0 context.Context, arg1 []string) ([]*cloud.Subnet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeSubnets", arg0, arg1)
+	ret0, _ := ret[0].([]*cloud.Subnet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeSubnets indicates an expected call of DescribeSubnets
+func (mr *MockCloudMockRecorder) DescribeSubnets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSubnets", reflect.TypeOf((*MockCloud)(nil).DescribeSubnets), arg0, arg1)
+}
+
+// FindSecurityGroups mocks base method
+func (m *MockCloud) FindSecurityGroups(arg0 context.Context, arg1 string, arg2 map[string]string, arg3 []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSecurityGroups", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSecurityGroups indicates an expected call of FindSecurityGroups
+func (mr *MockCloudMockRecorder) FindSecurityGroups(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSecurityGroups", reflect.TypeOf((*MockCloud)(nil).FindSecurityGroups), arg0, arg1, arg2, arg3)
+}
+
+// FindSubnets mocks base method
+func (m *MockCloud) FindSubnets(arg0 context.Context, arg1 string, arg2 map[string]string) ([]*cloud.Subnet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSubnets", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*cloud.Subnet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSubnets indicates an expected call of FindSubnets
+func (mr *MockCloudMockRecorder) FindSubnets(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSubnets", reflect.TypeOf((*MockCloud)(nil).FindSubnets), arg0, arg1, arg2)
+}
+
+// GetAccountId mocks base method
+func (m *MockCloud) GetAccountId(arg0 context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountId", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountId indicates an expected call of GetAccountId
+func (mr *MockCloudMockRecorder) GetAccountId(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountId", reflect.TypeOf((*MockCloud)(nil).GetAccountId), arg0)
+}
+
+// GetInstanceVpcId mocks base method
+func (m *MockCloud) GetInstanceVpcId(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceVpcId", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstanceVpcId indicates an expected call of GetInstanceVpcId
+func (mr *MockCloudMockRecorder) GetInstanceVpcId(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceVpcId", reflect.TypeOf((*MockCloud)(nil).GetInstanceVpcId), arg0, arg1)
+}
+
+// ResizeFileSystem mocks base method
+func (m *MockCloud) ResizeFileSystem(arg0 context.Context, arg1 string, arg2 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResizeFileSystem", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResizeFileSystem indicates an expected call of ResizeFileSystem
+func (mr *MockCloudMockRecorder) ResizeFileSystem(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizeFileSystem", reflect.TypeOf((*MockCloud)(nil).ResizeFileSystem), arg0, arg1, arg2)
+}
+
+// WaitForDataRepositoryTask mocks base method
+func (m *MockCloud) WaitForDataRepositoryTask(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForDataRepositoryTask", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForDataRepositoryTask indicates an expected call of WaitForDataRepositoryTask
+func (mr *MockCloudMockRecorder) WaitForDataRepositoryTask(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForDataRepositoryTask", reflect.TypeOf((*MockCloud)(nil).WaitForDataRepositoryTask), arg0, arg1)
+}
+
 // WaitForFileSystemAvailable mocks base method
 func (m *MockCloud) WaitForFileSystemAvailable(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -91,3 +284,17 @@ func (mr *MockCloudMockRecorder) WaitForFileSystemAvailable(arg0, arg1 interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForFileSystemAvailable", reflect.TypeOf((*MockCloud)(nil).WaitForFileSystemAvailable), arg0, arg1)
 }
+
+// WaitForFileSystemResize mocks base method
+func (m *MockCloud) WaitForFileSystemResize(arg0 context.Context, arg1 string, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForFileSystemResize", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForFileSystemResize indicates an expected call of WaitForFileSystemResize
+func (mr *MockCloudMockRecorder) WaitForFileSystemResize(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForFileSystemResize", reflect.TypeOf((*MockCloud)(nil).WaitForFileSystemResize), arg0, arg1, arg2)
+}
@@ -5,9 +5,11 @@
 package mocks
 
 import (
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	gomock "github.com/golang/mock/gomock"
 	mount "k8s.io/utils/mount"
 	reflect "reflect"
+	time "time"
 )
 
 // MockMounter is a mock of Mounter interface
@@ -33,6 +35,49 @@ func (m *MockMounter) EXPECT() *MockMounterMockRecorder {
 	return m.recorder
 }
 
+// CheckResponsive mocks base method
+func (m *MockMounter) CheckResponsive(arg0 string, arg1 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckResponsive", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckResponsive indicates an expected call of CheckResponsive
+func (mr *MockMounterMockRecorder) CheckResponsive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckResponsive", reflect.TypeOf((*MockMounter)(nil).CheckResponsive), arg0, arg1)
+}
+
+// ForceUnmount mocks base method
+func (m *MockMounter) ForceUnmount(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceUnmount", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceUnmount indicates an expected call of ForceUnmount
+func (mr *MockMounterMockRecorder) ForceUnmount(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceUnmount", reflect.TypeOf((*MockMounter)(nil).ForceUnmount), arg0)
+}
+
+// GetMountInfo mocks base method
+func (m *MockMounter) GetMountInfo(arg0 string) (*mount.MountInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMountInfo", arg0)
+	ret0, _ := ret[0].(*mount.MountInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMountInfo indicates an expected call of GetMountInfo
+func (mr *MockMounterMockRecorder) GetMountInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMountInfo", reflect.TypeOf((*MockMounter)(nil).GetMountInfo), arg0)
+}
+
 // GetMountRefs mocks base method
 func (m *MockMounter) GetMountRefs(arg0 string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -48,6 +93,21 @@ func (mr *MockMounterMockRecorder) GetMountRefs(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMountRefs", reflect.TypeOf((*MockMounter)(nil).GetMountRefs), arg0)
 }
 
+// GetVolumeUsage mocks base method
+func (m *MockMounter) GetVolumeUsage(arg0 string) ([]*csi.VolumeUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeUsage", arg0)
+	ret0, _ := ret[0].([]*csi.VolumeUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumeUsage indicates an expected call of GetVolumeUsage
+func (mr *MockMounterMockRecorder) GetVolumeUsage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeUsage", reflect.TypeOf((*MockMounter)(nil).GetVolumeUsage), arg0)
+}
+
 // IsLikelyNotMountPoint mocks base method
 func (m *MockMounter) IsLikelyNotMountPoint(arg0 string) (bool, error) {
 	m.ctrl.T.Helper()
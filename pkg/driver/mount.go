@@ -17,19 +17,39 @@ limitations under the License.
 package driver
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"k8s.io/klog"
 	"k8s.io/utils/mount"
 )
 
+// procMountInfoPath is the mount table of the driver's mount namespace
+const procMountInfoPath = "/proc/self/mountinfo"
+
 // Mounter is an interface for mount operations
 type Mounter interface {
 	mount.Interface
 	MakeDir(pathname string) error
+	CheckResponsive(pathname string, timeout time.Duration) error
+	GetVolumeUsage(pathname string) ([]*csi.VolumeUsage, error)
+	GetMountInfo(pathname string) (*mount.MountInfo, error)
+	ForceUnmount(target string) error
 }
 
 type NodeMounter struct {
 	mount.Interface
+
+	// pendingChecks holds the paths whose responsiveness check hasn't returned
+	// yet, so that a hung mount blocks one goroutine at most.
+	pendingChecksMux sync.Mutex
+	pendingChecks    map[string]bool
 }
 
 func newNodeMounter() Mounter {
@@ -47,3 +67,101 @@ func (m *NodeMounter) MakeDir(pathname string) error {
 	}
 	return nil
 }
+
+// CheckResponsive stats the path and returns an error if the stat fails or
+// doesn't return within timeout, as happens when a Lustre mount hangs. While
+// the stat of a previous check of the path hasn't returned, an error is
+// returned right away.
+func (m *NodeMounter) CheckResponsive(pathname string, timeout time.Duration) error {
+	m.pendingChecksMux.Lock()
+	if m.pendingChecks[pathname] {
+		m.pendingChecksMux.Unlock()
+		return fmt.Errorf("a previous stat of %s has not returned yet", pathname)
+	}
+	if m.pendingChecks == nil {
+		m.pendingChecks = map[string]bool{}
+	}
+	m.pendingChecks[pathname] = true
+	m.pendingChecksMux.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := os.Stat(pathname)
+		m.pendingChecksMux.Lock()
+		delete(m.pendingChecks, pathname)
+		m.pendingChecksMux.Unlock()
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("stat %s did not return within %v", pathname, timeout)
+	}
+}
+
+// GetVolumeUsage returns the capacity and inode usage of the filesystem
+// mounted at the path.
+func (m *NodeMounter) GetVolumeUsage(pathname string) ([]*csi.VolumeUsage, error) {
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(pathname, &statfs); err != nil {
+		return nil, err
+	}
+	return newVolumeUsage(&statfs), nil
+}
+
+// newVolumeUsage converts filesystem statistics to volume usage. Available
+// space is the space available to unprivileged users, while used space
+// includes the blocks reserved for root.
+func newVolumeUsage(statfs *syscall.Statfs_t) []*csi.VolumeUsage {
+	blockSize := int64(statfs.Bsize)
+	return []*csi.VolumeUsage{
+		{
+			Available: int64(statfs.Bavail) * blockSize,
+			Total:     int64(statfs.Blocks) * blockSize,
+			Used:      (int64(statfs.Blocks) - int64(statfs.Bfree)) * blockSize,
+			Unit:      csi.VolumeUsage_BYTES,
+		},
+		{
+			Available: int64(statfs.Ffree),
+			Total:     int64(statfs.Files),
+			Used:      int64(statfs.Files) - int64(statfs.Ffree),
+			Unit:      csi.VolumeUsage_INODES,
+		},
+	}
+}
+
+// GetMountInfo returns the mount at the path, the topmost one if several are
+// stacked there, or nil if the path isn't a mount point. Unlike a stat of the
+// path it doesn't block on a hung Lustre mount.
+func (m *NodeMounter) GetMountInfo(pathname string) (*mount.MountInfo, error) {
+	infos, err := mount.ParseMountInfo(procMountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	pathname = filepath.Clean(pathname)
+	var info *mount.MountInfo
+	for i := range infos {
+		if infos[i].MountPoint == pathname {
+			info = &infos[i]
+		}
+	}
+	return info, nil
+}
+
+// ForceUnmount unmounts the target aborting the pending requests to the
+// filesystem, which a stale Lustre mount needs. If that fails, the target is
+// lazily unmounted, detaching it right away and cleaning it up once it is no
+// longer busy.
+func (m *NodeMounter) ForceUnmount(target string) error {
+	out, err := exec.Command("umount", "-f", target).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	klog.Warningf("Force unmount of %s failed, unmounting it lazily: %v: %s", target, err, out)
+	if out, err := exec.Command("umount", "-l", target).CombinedOutput(); err != nil {
+		return fmt.Errorf("lazy unmount of %s failed: %v: %s", target, err, out)
+	}
+	return nil
+}
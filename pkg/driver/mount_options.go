@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lustreMountOptions are the Lustre client mount options that may be set per
+// volume, each mapped to the group of options it conflicts with.
+var lustreMountOptions = map[string]string{
+	"flock":           "flock",
+	"localflock":      "flock",
+	"noflock":         "flock",
+	"user_xattr":      "user_xattr",
+	"nouser_xattr":    "user_xattr",
+	"user_fid2path":   "user_fid2path",
+	"nouser_fid2path": "user_fid2path",
+	"lazystatfs":      "lazystatfs",
+	"nolazystatfs":    "lazystatfs",
+	"always_ping":     "always_ping",
+	"verbose":         "verbose",
+	"noverbose":       "verbose",
+	"noatime":         "atime",
+	"relatime":        "atime",
+	"nodiratime":      "nodiratime",
+}
+
+// parseLustreMountOptions parses a comma separated list of Lustre mount
+// options, rejecting options that aren't allowed.
+func parseLustreMountOptions(val string) ([]string, error) {
+	options := []string{}
+	if val == "" {
+		return options, nil
+	}
+	for _, opt := range strings.Split(val, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		if _, ok := lustreMountOptions[opt]; !ok {
+			return nil, fmt.Errorf("mount option %q is not supported", opt)
+		}
+		options = append(options, opt)
+	}
+	return mergeMountOptions(options, nil)
+}
+
+// mergeMountOptions appends the options to the mount flags, dropping
+// duplicates. It fails if two Lustre options of the same group are set.
+func mergeMountOptions(flags, options []string) ([]string, error) {
+	merged := []string{}
+	groups := map[string]string{}
+	for _, opt := range append(append([]string{}, flags...), options...) {
+		if containsOption(merged, opt) {
+			continue
+		}
+		if group, ok := lustreMountOptions[opt]; ok {
+			if other, ok := groups[group]; ok {
+				return nil, fmt.Errorf("mount options %q and %q conflict", other, opt)
+			}
+			groups[group] = opt
+		}
+		merged = append(merged, opt)
+	}
+	return merged, nil
+}
+
+func containsOption(options []string, opt string) bool {
+	for _, o := range options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
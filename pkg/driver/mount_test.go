@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestNewVolumeUsage(t *testing.T) {
+	statfs := &syscall.Statfs_t{
+		Bsize:  4096,
+		Blocks: 1000,
+		Bfree:  300,
+		Bavail: 200,
+		Files:  100,
+		Ffree:  90,
+	}
+
+	usage := newVolumeUsage(statfs)
+	if len(usage) != 2 {
+		t.Fatalf("Number of usages mismatches. actual: %v expected: %v", len(usage), 2)
+	}
+
+	bytes := usage[0]
+	if bytes.Unit != csi.VolumeUsage_BYTES {
+		t.Fatalf("Unit mismatches. actual: %v expected: %v", bytes.Unit, csi.VolumeUsage_BYTES)
+	}
+	if bytes.Total != 1000*4096 || bytes.Available != 200*4096 || bytes.Used != 700*4096 {
+		t.Fatalf("Byte usage mismatches. actual: %+v expected total: %v available: %v used: %v", bytes, 1000*4096, 200*4096, 700*4096)
+	}
+
+	inodes := usage[1]
+	if inodes.Unit != csi.VolumeUsage_INODES {
+		t.Fatalf("Unit mismatches. actual: %v expected: %v", inodes.Unit, csi.VolumeUsage_INODES)
+	}
+	if inodes.Total != 100 || inodes.Available != 90 || inodes.Used != 10 {
+		t.Fatalf("Inode usage mismatches. actual: %+v expected total: %v available: %v used: %v", inodes, 100, 90, 10)
+	}
+}
@@ -19,23 +19,37 @@ package driver
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/klog"
+	"k8s.io/utils/mount"
 )
 
 var (
 	nodeCaps = []csi.NodeServiceCapability_RPC_Type{
 		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
 	}
+
+	// lookupHost resolves the DNS name of a filesystem, replaced in tests
+	lookupHost = net.LookupHost
 )
 
-func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	klog.V(4).Infof("NodeStageVolume: called with args %+v", req)
+const (
+	// volumeHealthCheckTimeout is how long a stat of a volume may take
+	// before the volume is reported as abnormal
+	volumeHealthCheckTimeout = 10 * time.Second
+)
 
+func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -69,23 +83,47 @@ func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRe
 		return nil, status.Error(codes.InvalidArgument, "Volume capability not supported")
 	}
 
-	mountOptions := []string{}
-
+	// Lustre options from the volume context are validated here too, as
+	// statically provisioned volumes set them without going through the
+	// controller
+	lustreOptions, err := parseLustreMountOptions(context[volumeContextLustreMountOptions])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid %s: %v", volumeContextLustreMountOptions, err)
+	}
+	var mountFlags []string
 	if m := volCap.GetMount(); m != nil {
-		hasOption := func(options []string, opt string) bool {
-			for _, o := range options {
-				if o == opt {
-					return true
-				}
-			}
-			return false
+		mountFlags = m.MountFlags
+	}
+	mountOptions, err := mergeMountOptions(mountFlags, lustreOptions)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid mount options: %v", err)
+	}
+	lustreParams, err := parseLustreParams(context)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid Lustre parameter: %v", err)
+	}
+
+	// a retry after the driver restarted, or after a previous call timed out,
+	// finds the volume already staged
+	mnt, err := d.mounter.GetMountInfo(target)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not check if %q is mounted: %v", target, err)
+	}
+	if mnt != nil {
+		ok, err := isLustreSource(mnt, dnsname, mountname)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not resolve %q: %v", dnsname, err)
 		}
-		for _, f := range m.MountFlags {
-			if !hasOption(mountOptions, f) {
-				mountOptions = append(mountOptions, f)
-			}
+		if !ok {
+			return nil, status.Errorf(codes.AlreadyExists, "%q is already mounted from %q", target, mnt.Source)
+		}
+		if containsOption(mnt.MountOptions, "ro") != containsOption(mountOptions, "ro") {
+			return nil, status.Errorf(codes.AlreadyExists, "%q is already mounted with options %v", target, mnt.MountOptions)
 		}
+		klog.V(4).Infof("NodeStageVolume: %s is already mounted at %s", source, target)
+		return &csi.NodeStageVolumeResponse{}, nil
 	}
+
 	klog.V(5).Infof("NodeStageVolume: creating dir %s", target)
 	if err := d.mounter.MakeDir(target); err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not create dir %q: %v", target, err)
@@ -97,12 +135,22 @@ func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRe
 		return nil, status.Errorf(codes.Internal, "Could not mount %q at %q: %v", source, target, err)
 	}
 
+	if len(lustreParams) > 0 {
+		klog.V(5).Infof("NodeStageVolume: setting Lustre parameters %v of %s", lustreParams, target)
+		if err := d.setLustreParams(target, lustreParams); err != nil {
+			if err := d.mounter.Unmount(target); err != nil {
+				klog.Warningf("NodeStageVolume: could not unmount %s: %v", target, err)
+			} else {
+				os.Remove(target)
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
 func (d *Driver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	klog.V(4).Infof("NodeUnstageVolume: called with args %+v", req)
-
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -114,17 +162,14 @@ func (d *Driver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolu
 	}
 
 	klog.V(5).Infof("NodeUnstageVolume: unmounting %s", target)
-	err := d.mounter.Unmount(target)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Could not unmount %q: %v", target, err)
+	if err := d.cleanupMountPoint(target); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
 func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	klog.V(4).Infof("NodePublishVolume: called with args %+v", req)
-
 	context := req.GetVolumeContext()
 
 	target := req.GetTargetPath()
@@ -142,6 +187,28 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 	}
 
 	subpath := context[volumeContextSubPath]
+
+	mnt, err := d.mounter.GetMountInfo(target)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not check if %q is mounted: %v", target, err)
+	}
+	if mnt != nil {
+		// the target must be a bind mount of the same directory of the
+		// staged filesystem
+		staged, err := d.mounter.GetMountInfo(stagingTarget)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not check if %q is mounted: %v", stagingTarget, err)
+		}
+		if staged == nil || staged.MajorMinor != mnt.MajorMinor || mnt.Root != path.Join(staged.Root, subpath) {
+			return nil, status.Errorf(codes.AlreadyExists, "%q is already mounted from %q", target, path.Join(mnt.Source, mnt.Root))
+		}
+		if containsOption(mnt.MountOptions, "ro") != req.GetReadonly() {
+			return nil, status.Errorf(codes.AlreadyExists, "%q is already mounted with options %v", target, mnt.MountOptions)
+		}
+		klog.V(4).Infof("NodePublishVolume: %s is already mounted at %s", stagingTarget, target)
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
 	if subpath != "" {
 		stagingTarget = fmt.Sprintf("%s/%s", stagingTarget, subpath)
 	}
@@ -166,8 +233,6 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 }
 
 func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
-	klog.V(4).Infof("NodeUnpublishVolume: called with args %+v", req)
-
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -179,16 +244,77 @@ func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublish
 	}
 
 	klog.V(5).Infof("NodeUnpublishVolume: unmounting %s", target)
-	err := d.mounter.Unmount(target)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Could not unmount %q: %v", target, err)
+	if err := d.cleanupMountPoint(target); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
 func (d *Driver) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	volumePath := req.GetVolumePath()
+	if len(volumePath) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume path not provided")
+	}
+
+	// Check that the mounts respond first, any other file operation on a
+	// hung Lustre mount would block the call. The staged Lustre mount is
+	// checked too, the volume path may be a bind mount of a subpath of it.
+	paths := []string{volumePath}
+	if stagingPath := req.GetStagingTargetPath(); stagingPath != "" {
+		paths = append(paths, stagingPath)
+	}
+	for _, path := range paths {
+		if err := d.mounter.CheckResponsive(path, volumeHealthCheckTimeout); err != nil {
+			if os.IsNotExist(err) {
+				return nil, status.Errorf(codes.NotFound, "Path %q not found", path)
+			}
+			klog.Warningf("NodeGetVolumeStats: volume %s at %s is not responsive: %v", volumeID, path, err)
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: &csi.VolumeCondition{
+					Abnormal: true,
+					Message:  fmt.Sprintf("Volume is not responsive: %v", err),
+				},
+			}, nil
+		}
+	}
+
+	notMnt, err := d.mounter.IsLikelyNotMountPoint(volumePath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not determine if %q is a mount point: %v", volumePath, err)
+	}
+	if notMnt {
+		return nil, status.Errorf(codes.NotFound, "Volume path %q is not mounted", volumePath)
+	}
+
+	usage, err := d.mounter.GetVolumeUsage(volumePath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not get stats of %q: %v", volumePath, err)
+	}
+	// a shared volume limited by a project quota only has the capacity of
+	// its quota, not of the whole filesystem
+	if strings.HasPrefix(volumeID, sharedVolumeIdPrefix) {
+		quotaUsage, err := d.getProjectQuotaUsage(volumePath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not get quota of %q: %v", volumePath, err)
+		}
+		if quotaUsage != nil {
+			usage = append([]*csi.VolumeUsage{quotaUsage}, usage[1:]...)
+		}
+	}
+
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: usage,
+		VolumeCondition: &csi.VolumeCondition{
+			Abnormal: false,
+			Message:  "Volume is responsive",
+		},
+	}, nil
 }
 
 func (d *Driver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
@@ -196,7 +322,6 @@ func (d *Driver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolume
 }
 
 func (d *Driver) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
-	klog.V(4).Infof("NodeGetCapabilities: called with args %+v", req)
 	var caps []*csi.NodeServiceCapability
 	for _, cap := range nodeCaps {
 		c := &csi.NodeServiceCapability{
@@ -212,9 +337,65 @@ func (d *Driver) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabi
 }
 
 func (d *Driver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
-	klog.V(4).Infof("NodeGetInfo: called with args %+v", req)
-
 	return &csi.NodeGetInfoResponse{
 		NodeId: d.nodeID,
+		AccessibleTopology: &csi.Topology{
+			Segments: map[string]string{
+				TopologyKey: d.availabilityZone,
+			},
+		},
 	}, nil
 }
+
+// cleanupMountPoint unmounts the target if it is mounted and removes the
+// directory. A target that doesn't exist or isn't mounted anymore, as after a
+// retry, is cleaned up already. A mount that doesn't respond, like a stale
+// Lustre mount failing with "transport endpoint is not connected", is force
+// unmounted, a plain unmount of it would fail or block.
+func (d *Driver) cleanupMountPoint(target string) error {
+	mnt, err := d.mounter.GetMountInfo(target)
+	if err != nil {
+		return fmt.Errorf("Could not check if %q is mounted: %v", target, err)
+	}
+	if mnt != nil {
+		if err := d.mounter.CheckResponsive(target, volumeHealthCheckTimeout); err != nil {
+			klog.Warningf("Mount at %s is corrupted, force unmounting it: %v", target, err)
+			if err := d.mounter.ForceUnmount(target); err != nil {
+				return fmt.Errorf("Could not force unmount %q: %v", target, err)
+			}
+		} else if err := d.mounter.Unmount(target); err != nil {
+			return fmt.Errorf("Could not unmount %q: %v", target, err)
+		}
+	} else {
+		klog.V(4).Infof("%s is not mounted", target)
+	}
+
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Could not remove %q: %v", target, err)
+	}
+	return nil
+}
+
+// isLustreSource returns whether the mount is of the Lustre filesystem. The
+// kernel reports the source with the DNS name resolved to the NID of the
+// server, so the source is compared with the addresses of the DNS name too.
+// The filesystem name alone doesn't identify a filesystem, e.g. all SCRATCH_1
+// filesystems are named fsx.
+func isLustreSource(mnt *mount.MountInfo, dnsname, mountname string) (bool, error) {
+	if mnt.FsType != "lustre" {
+		return false, nil
+	}
+	if mnt.Source == fmt.Sprintf("%s@tcp:/%s", dnsname, mountname) {
+		return true, nil
+	}
+	addrs, err := lookupHost(dnsname)
+	if err != nil {
+		return false, err
+	}
+	for _, addr := range addrs {
+		if mnt.Source == fmt.Sprintf("%s@tcp:/%s", addr, mountname) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
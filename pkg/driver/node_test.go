@@ -19,11 +19,14 @@ package driver
 import (
 	"context"
 	"fmt"
+	"os"
+	"reflect"
 	"testing"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/driver/mocks"
+	"k8s.io/utils/mount"
 )
 
 func TestNodePublishVolume(t *testing.T) {
@@ -59,6 +62,7 @@ func TestNodePublishVolume(t *testing.T) {
 	successfulDriverWithOptions := func(mountOptions []string) func(*gomock.Controller) *Driver {
 		return func(mockCtrl *gomock.Controller) *Driver {
 			driver, mockMounter := mockDriver(mockCtrl)
+			mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(nil, nil)
 			mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
 			mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
 			mockMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(""), gomock.Eq(mountOptions)).Return(nil)
@@ -98,6 +102,7 @@ func TestNodePublishVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				stagingPathWithSubPath := "/staging/target/path/subpath"
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(nil, nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(stagingPathWithSubPath)).Return(nil)
 				mockMounter.EXPECT().Mount(gomock.Eq(stagingPathWithSubPath), gomock.Eq(targetPath), gomock.Eq(""), gomock.Eq([]string{"bind"})).Return(nil)
@@ -119,6 +124,60 @@ func TestNodePublishVolume(t *testing.T) {
 				return req
 			},
 		},
+		{
+			name: "success: volume already published",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/", MountOptions: []string{"rw"}}, nil)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/", MountOptions: []string{"rw"}}, nil)
+				return driver
+			},
+			request: standardRequest,
+		},
+		{
+			name: "success: subpath already published",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/" + subpath, MountOptions: []string{"rw"}}, nil)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/", MountOptions: []string{"rw"}}, nil)
+				return driver
+			},
+			request: func() *csi.NodePublishVolumeRequest {
+				req := standardRequest()
+				req.VolumeContext[volumeContextSubPath] = subpath
+				return req
+			},
+		},
+		{
+			name: "fail: target path mounted from another subpath",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/other", MountOptions: []string{"rw"}}, nil)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/", MountOptions: []string{"rw"}}, nil)
+				return driver
+			},
+			request: func() *csi.NodePublishVolumeRequest {
+				req := standardRequest()
+				req.VolumeContext[volumeContextSubPath] = subpath
+				return req
+			},
+			expectError: true,
+		},
+		{
+			name: "fail: volume already published read-write",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/", MountOptions: []string{"rw"}}, nil)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MajorMinor: "0:52", Root: "/", MountOptions: []string{"rw"}}, nil)
+				return driver
+			},
+			request: func() *csi.NodePublishVolumeRequest {
+				req := standardRequest()
+				req.Readonly = true
+				return req
+			},
+			expectError: true,
+		},
 		{
 			name: "fail: missing target path",
 			driver: func(mockCtrl *gomock.Controller) *Driver {
@@ -150,6 +209,7 @@ func TestNodePublishVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				err := fmt.Errorf("failed to MakeDir")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(nil, nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(err)
 
 				return driver
@@ -162,6 +222,7 @@ func TestNodePublishVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				err := fmt.Errorf("failed to Mount")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(nil, nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
 				mockMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(""), gomock.Eq([]string{"bind"})).Return(err)
@@ -227,11 +288,34 @@ func TestNodeUnpublishVolume(t *testing.T) {
 			name: "success: normal",
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MountPoint: targetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(targetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(targetPath)).Return(nil)
 				return driver
 			},
 			request: standardRequest,
 		},
+		{
+			name: "success: target is not mounted",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(nil, nil)
+				return driver
+			},
+			request: standardRequest,
+		},
+		{
+			name: "success: corrupted mount is force unmounted",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				statErr := fmt.Errorf("stat %s: transport endpoint is not connected", targetPath)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MountPoint: targetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(targetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(statErr)
+				mockMounter.EXPECT().ForceUnmount(gomock.Eq(targetPath)).Return(nil)
+				return driver
+			},
+			request: standardRequest,
+		},
 		{
 			name: "fail: targetPath is missing",
 			driver: func(mockCtrl *gomock.Controller) *Driver {
@@ -250,12 +334,38 @@ func TestNodeUnpublishVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				mountErr := fmt.Errorf("Unmount failed")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MountPoint: targetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(targetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(targetPath)).Return(mountErr)
 				return driver
 			},
 			request:     standardRequest,
 			expectError: true,
 		},
+		{
+			name: "fail: mounter failed to force umount",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				statErr := fmt.Errorf("stat %s: transport endpoint is not connected", targetPath)
+				mountErr := fmt.Errorf("Unmount failed")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(&mount.MountInfo{MountPoint: targetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(targetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(statErr)
+				mockMounter.EXPECT().ForceUnmount(gomock.Eq(targetPath)).Return(mountErr)
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+		{
+			name: "fail: mounter failed to read the mount table",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(targetPath)).Return(nil, fmt.Errorf("open /proc/self/mountinfo: permission denied"))
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -287,6 +397,14 @@ func TestNodeStageVolume(t *testing.T) {
 		lustreSource      = dnsname + "@tcp:/" + mountname
 	)
 
+	defer func(lookup func(string) ([]string, error)) { lookupHost = lookup }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		if host != dnsname {
+			return nil, fmt.Errorf("no such host %s", host)
+		}
+		return []string{"172.31.10.20"}, nil
+	}
+
 	mockDriver := func(mockCtrl *gomock.Controller) (*Driver, *mocks.MockMounter) {
 		mockMounter := mocks.NewMockMounter(mockCtrl)
 		driver := &Driver{
@@ -300,6 +418,7 @@ func TestNodeStageVolume(t *testing.T) {
 	successfulDriverWithOptions := func(mountOptions []string) func(*gomock.Controller) *Driver {
 		return func(mockCtrl *gomock.Controller) *Driver {
 			driver, mockMounter := mockDriver(mockCtrl)
+			mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, nil)
 			mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
 			mockMounter.EXPECT().Mount(gomock.Eq(lustreSource), gomock.Eq(stagingTargetPath), gomock.Eq("lustre"), gomock.Eq(mountOptions)).Return(nil)
 			return driver
@@ -345,6 +464,165 @@ func TestNodeStageVolume(t *testing.T) {
 				return req
 			},
 		},
+		{
+			name:   "success: lustre mount options merged with mount flags",
+			driver: successfulDriverWithOptions([]string{"flock", "noatime", "user_xattr"}),
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				(req.VolumeCapability.AccessType).(*csi.VolumeCapability_Mount).Mount.MountFlags = []string{"flock", "noatime"}
+				req.VolumeContext[volumeContextLustreMountOptions] = "noatime,user_xattr"
+				return req
+			},
+		},
+		{
+			name: "fail: unsupported lustre mount option",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, _ := mockDriver(mockCtrl)
+				return driver
+			},
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				req.VolumeContext[volumeContextLustreMountOptions] = "flock,suid"
+				return req
+			},
+			expectError: true,
+		},
+		{
+			name: "fail: lustre mount option conflicts with mount flags",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, _ := mockDriver(mockCtrl)
+				return driver
+			},
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				(req.VolumeCapability.AccessType).(*csi.VolumeCapability_Mount).Mount.MountFlags = []string{"flock"}
+				req.VolumeContext[volumeContextLustreMountOptions] = "localflock"
+				return req
+			},
+			expectError: true,
+		},
+		{
+			name: "success: lustre parameters set on the instances of the mount",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockExecutor := mocks.NewMockExecutor(mockCtrl)
+				driver.executor = mockExecutor
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, nil)
+				mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(lustreSource), gomock.Eq(stagingTargetPath), gomock.Eq("lustre"), gomock.Eq([]string{})).Return(nil)
+				mockExecutor.EXPECT().Run("lfs", "getname", stagingTargetPath).Return([]byte("random-ffff9a2b3c4d5e6f "+stagingTargetPath+"\n"), nil)
+				mockExecutor.EXPECT().Run("lctl", "set_param",
+					"llite.random-ffff9a2b3c4d5e6f.max_cached_mb=32",
+					"osc.random-*-osc-ffff9a2b3c4d5e6f.max_rpcs_in_flight=64",
+					"osc.random-*-osc-ffff9a2b3c4d5e6f.checksums=0",
+				).Return(nil, nil)
+				return driver
+			},
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				req.VolumeContext[volumeContextMaxCachedMB] = "32"
+				req.VolumeContext[volumeContextMaxRpcsInFlight] = "64"
+				req.VolumeContext[volumeContextChecksums] = "false"
+				return req
+			},
+		},
+		{
+			name: "fail: invalid lustre parameter",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, _ := mockDriver(mockCtrl)
+				return driver
+			},
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				req.VolumeContext[volumeContextMaxRpcsInFlight] = "1000"
+				return req
+			},
+			expectError: true,
+		},
+		{
+			name: "fail: lctl failed to set lustre parameters",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockExecutor := mocks.NewMockExecutor(mockCtrl)
+				driver.executor = mockExecutor
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, nil)
+				mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(lustreSource), gomock.Eq(stagingTargetPath), gomock.Eq("lustre"), gomock.Eq([]string{})).Return(nil)
+				mockExecutor.EXPECT().Run("lfs", "getname", stagingTargetPath).Return([]byte("random-ffff9a2b3c4d5e6f "+stagingTargetPath+"\n"), nil)
+				mockExecutor.EXPECT().Run("lctl", "set_param", "llite.random-ffff9a2b3c4d5e6f.max_cached_mb=32").Return([]byte("permission denied"), fmt.Errorf("exit status 1"))
+				mockMounter.EXPECT().Unmount(gomock.Eq(stagingTargetPath)).Return(nil)
+				return driver
+			},
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				req.VolumeContext[volumeContextMaxCachedMB] = "32"
+				return req
+			},
+			expectError: true,
+		},
+		{
+			name: "success: volume already staged",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{
+					Source:       "172.31.10.20@tcp:/" + mountname,
+					FsType:       "lustre",
+					MountPoint:   stagingTargetPath,
+					MountOptions: []string{"rw", "relatime"},
+				}, nil)
+				return driver
+			},
+			request: standardRequest,
+		},
+		{
+			name: "fail: staging target path mounted from another filesystem",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{
+					Source:       "172.31.10.21@tcp:/other",
+					FsType:       "lustre",
+					MountPoint:   stagingTargetPath,
+					MountOptions: []string{"rw", "relatime"},
+				}, nil)
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+		{
+			name: "fail: staging target path mounted from another filesystem of the same name",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{
+					Source:       "172.31.10.21@tcp:/" + mountname,
+					FsType:       "lustre",
+					MountPoint:   stagingTargetPath,
+					MountOptions: []string{"rw", "relatime"},
+				}, nil)
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+		{
+			name: "fail: volume already staged read-write",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{
+					Source:       "172.31.10.20@tcp:/" + mountname,
+					FsType:       "lustre",
+					MountPoint:   stagingTargetPath,
+					MountOptions: []string{"rw", "relatime"},
+				}, nil)
+				return driver
+			},
+			request: func() *csi.NodeStageVolumeRequest {
+				req := standardRequest()
+				(req.VolumeCapability.AccessType).(*csi.VolumeCapability_Mount).Mount.MountFlags = []string{"ro"}
+				return req
+			},
+			expectError: true,
+		},
 		{
 			name: "fail: missing dns name",
 			driver: func(mockCtrl *gomock.Controller) *Driver {
@@ -376,6 +654,7 @@ func TestNodeStageVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				err := fmt.Errorf("failed to MakeDir")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(err)
 
 				return driver
@@ -388,6 +667,7 @@ func TestNodeStageVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				err := fmt.Errorf("failed to Mount")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, nil)
 				mockMounter.EXPECT().MakeDir(gomock.Eq(stagingTargetPath)).Return(nil)
 				mockMounter.EXPECT().Mount(gomock.Eq(lustreSource), gomock.Eq(stagingTargetPath), gomock.Eq("lustre"), gomock.Eq([]string{})).Return(err)
 
@@ -478,11 +758,34 @@ func TestNodeUnstageVolume(t *testing.T) {
 			name: "success: normal",
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MountPoint: stagingTargetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(stagingTargetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(stagingTargetPath)).Return(nil)
 				return driver
 			},
 			request: standardRequest,
 		},
+		{
+			name: "success: target is not mounted",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, nil)
+				return driver
+			},
+			request: standardRequest,
+		},
+		{
+			name: "success: corrupted mount is force unmounted",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				statErr := fmt.Errorf("stat %s: transport endpoint is not connected", stagingTargetPath)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MountPoint: stagingTargetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(stagingTargetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(statErr)
+				mockMounter.EXPECT().ForceUnmount(gomock.Eq(stagingTargetPath)).Return(nil)
+				return driver
+			},
+			request: standardRequest,
+		},
 		{
 			name: "fail: stagingTargetPath is missing",
 			driver: func(mockCtrl *gomock.Controller) *Driver {
@@ -501,12 +804,38 @@ func TestNodeUnstageVolume(t *testing.T) {
 			driver: func(mockCtrl *gomock.Controller) *Driver {
 				driver, mockMounter := mockDriver(mockCtrl)
 				mountErr := fmt.Errorf("Unmount failed")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MountPoint: stagingTargetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(stagingTargetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(stagingTargetPath)).Return(mountErr)
 				return driver
 			},
 			request:     standardRequest,
 			expectError: true,
 		},
+		{
+			name: "fail: mounter failed to force umount",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				statErr := fmt.Errorf("stat %s: transport endpoint is not connected", stagingTargetPath)
+				mountErr := fmt.Errorf("Unmount failed")
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(&mount.MountInfo{MountPoint: stagingTargetPath}, nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(stagingTargetPath), gomock.Eq(volumeHealthCheckTimeout)).Return(statErr)
+				mockMounter.EXPECT().ForceUnmount(gomock.Eq(stagingTargetPath)).Return(mountErr)
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+		{
+			name: "fail: mounter failed to read the mount table",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().GetMountInfo(gomock.Eq(stagingTargetPath)).Return(nil, fmt.Errorf("open /proc/self/mountinfo: permission denied"))
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -526,3 +855,246 @@ func TestNodeUnstageVolume(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeGetVolumeStats(t *testing.T) {
+
+	var (
+		endpoint    = "endpoint"
+		nodeID      = "nodeID"
+		volumePath  = "/volume/path"
+		stagingPath = "/staging/path"
+		usage       = []*csi.VolumeUsage{
+			{
+				Available: 1000,
+				Total:     1200,
+				Used:      200,
+				Unit:      csi.VolumeUsage_BYTES,
+			},
+			{
+				Available: 90,
+				Total:     100,
+				Used:      10,
+				Unit:      csi.VolumeUsage_INODES,
+			},
+		}
+	)
+
+	mockDriver := func(mockCtrl *gomock.Controller) (*Driver, *mocks.MockMounter) {
+		mockMounter := mocks.NewMockMounter(mockCtrl)
+		driver := &Driver{
+			endpoint: endpoint,
+			nodeID:   nodeID,
+			mounter:  mockMounter,
+		}
+		return driver, mockMounter
+	}
+
+	standardRequest := func() *csi.NodeGetVolumeStatsRequest {
+		return &csi.NodeGetVolumeStatsRequest{
+			VolumeId:   "volumeId",
+			VolumePath: volumePath,
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		driver         func(mockCtrl *gomock.Controller) *Driver
+		request        func() *csi.NodeGetVolumeStatsRequest
+		expectAbnormal bool
+		expectUsage    []*csi.VolumeUsage
+		expectError    bool
+	}{
+		{
+			name: "success: normal",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(volumePath)).Return(false, nil)
+				mockMounter.EXPECT().GetVolumeUsage(gomock.Eq(volumePath)).Return(usage, nil)
+				return driver
+			},
+			request: standardRequest,
+		},
+		{
+			name: "success: staged volume",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(stagingPath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(volumePath)).Return(false, nil)
+				mockMounter.EXPECT().GetVolumeUsage(gomock.Eq(volumePath)).Return(usage, nil)
+				return driver
+			},
+			request: func() *csi.NodeGetVolumeStatsRequest {
+				req := standardRequest()
+				req.StagingTargetPath = stagingPath
+				return req
+			},
+		},
+		{
+			name: "success: shared volume with a project quota",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockExecutor := mocks.NewMockExecutor(mockCtrl)
+				driver.executor = mockExecutor
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(volumePath)).Return(false, nil)
+				mockMounter.EXPECT().GetVolumeUsage(gomock.Eq(volumePath)).Return(usage, nil)
+				mockExecutor.EXPECT().Run("lfs", "project", "-d", volumePath).Return([]byte(" 1234 P "+volumePath+"\n"), nil)
+				mockExecutor.EXPECT().Run("lfs", "quota", "-q", "-p", "1234", volumePath).Return([]byte("   /volume/path     100       0    1000       -       5       0       0       -\n"), nil)
+				return driver
+			},
+			request: func() *csi.NodeGetVolumeStatsRequest {
+				req := standardRequest()
+				req.VolumeId = "shared/fs-1234/volumeId"
+				return req
+			},
+			expectUsage: []*csi.VolumeUsage{
+				{
+					Available: 900 * 1024,
+					Total:     1000 * 1024,
+					Used:      100 * 1024,
+					Unit:      csi.VolumeUsage_BYTES,
+				},
+				usage[1],
+			},
+		},
+		{
+			name: "success: shared volume without a project quota",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockExecutor := mocks.NewMockExecutor(mockCtrl)
+				driver.executor = mockExecutor
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(volumePath)).Return(false, nil)
+				mockMounter.EXPECT().GetVolumeUsage(gomock.Eq(volumePath)).Return(usage, nil)
+				mockExecutor.EXPECT().Run("lfs", "project", "-d", volumePath).Return([]byte("    0 - "+volumePath+"\n"), nil)
+				return driver
+			},
+			request: func() *csi.NodeGetVolumeStatsRequest {
+				req := standardRequest()
+				req.VolumeId = "shared/fs-1234/volumeId"
+				return req
+			},
+		},
+		{
+			name: "success: staged volume is not responsive",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(stagingPath), gomock.Any()).Return(fmt.Errorf("stat timed out"))
+				return driver
+			},
+			request: func() *csi.NodeGetVolumeStatsRequest {
+				req := standardRequest()
+				req.StagingTargetPath = stagingPath
+				return req
+			},
+			expectAbnormal: true,
+		},
+		{
+			name: "success: volume is not responsive",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(fmt.Errorf("stat timed out"))
+				return driver
+			},
+			request:        standardRequest,
+			expectAbnormal: true,
+		},
+		{
+			name: "fail: volume path is missing",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, _ := mockDriver(mockCtrl)
+				return driver
+			},
+			request: func() *csi.NodeGetVolumeStatsRequest {
+				req := standardRequest()
+				req.VolumePath = ""
+				return req
+			},
+			expectError: true,
+		},
+		{
+			name: "fail: volume path does not exist",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(os.ErrNotExist)
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+		{
+			name: "fail: volume path is not a mount point",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(volumePath)).Return(true, nil)
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+		{
+			name: "fail: mounter failed to get usage",
+			driver: func(mockCtrl *gomock.Controller) *Driver {
+				driver, mockMounter := mockDriver(mockCtrl)
+				mockMounter.EXPECT().CheckResponsive(gomock.Eq(volumePath), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(volumePath)).Return(false, nil)
+				mockMounter.EXPECT().GetVolumeUsage(gomock.Eq(volumePath)).Return(nil, fmt.Errorf("statfs failed"))
+				return driver
+			},
+			request:     standardRequest,
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mockCtrl = gomock.NewController(t)
+				driver   = tc.driver(mockCtrl)
+				req      = tc.request()
+				ctx      = context.Background()
+			)
+			resp, err := driver.NodeGetVolumeStats(ctx, req)
+			if tc.expectError && err == nil {
+				t.Fatalf("NodeGetVolumeStats is not failed: %v", err)
+			} else if !tc.expectError && err != nil {
+				t.Fatalf("NodeGetVolumeStats is failed: %v", err)
+			}
+			if err == nil && resp.VolumeCondition.Abnormal != tc.expectAbnormal {
+				t.Fatalf("VolumeCondition mismatches. actual: %v expected abnormal: %v", resp.VolumeCondition, tc.expectAbnormal)
+			}
+			expectUsage := tc.expectUsage
+			if expectUsage == nil {
+				expectUsage = usage
+			}
+			if err == nil && !tc.expectAbnormal && !reflect.DeepEqual(resp.Usage, expectUsage) {
+				t.Fatalf("Usage mismatches. actual: %v expected: %v", resp.Usage, expectUsage)
+			}
+			mockCtrl.Finish()
+		})
+	}
+}
+
+func TestNodeGetInfo(t *testing.T) {
+	driver := &Driver{
+		endpoint:         "endpoint",
+		nodeID:           "nodeID",
+		availabilityZone: "us-west-2a",
+	}
+
+	resp, err := driver.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	if err != nil {
+		t.Fatalf("NodeGetInfo is failed: %v", err)
+	}
+
+	if resp.NodeId != "nodeID" {
+		t.Fatalf("NodeId mismatches. actual: %v expected: %v", resp.NodeId, "nodeID")
+	}
+
+	if zone := resp.AccessibleTopology.Segments[TopologyKey]; zone != "us-west-2a" {
+		t.Fatalf("Zone mismatches. actual: %v expected: %v", zone, "us-west-2a")
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"k8s.io/klog"
+)
+
+// maxProjectId is the largest Lustre project ID
+const maxProjectId = 1<<32 - 2
+
+// setProjectQuota limits the subpath of the filesystem mounted at root to
+// quotaBytes with a Lustre project quota. The subpath is given a project ID
+// that no other directory at the root of the filesystem has, inherited by
+// everything created in it, unless it has one already.
+func (d *Driver) setProjectQuota(root, subPath string, quotaBytes int64) error {
+	dir := filepath.Join(root, subPath)
+	projectId, err := d.getProjectId(dir)
+	if err != nil {
+		return err
+	}
+	if projectId == 0 {
+		projectId, err = d.chooseProjectId(root, subPath)
+		if err != nil {
+			return err
+		}
+		klog.V(4).Infof("Assigning project ID %d to subpath %s", projectId, subPath)
+		if out, err := d.executor.Run("lfs", "project", "-p", strconv.FormatUint(uint64(projectId), 10), "-s", "-r", dir); err != nil {
+			return fmt.Errorf("could not set the project ID of %s: %v: %s", subPath, err, out)
+		}
+	}
+
+	// lfs setquota takes limits in KiB
+	limitKiB := (quotaBytes + 1023) / 1024
+	if out, err := d.executor.Run("lfs", "setquota", "-p", strconv.FormatUint(uint64(projectId), 10), "-B", strconv.FormatInt(limitKiB, 10), root); err != nil {
+		return fmt.Errorf("could not set the quota of project %d: %v: %s", projectId, err, out)
+	}
+	return nil
+}
+
+// chooseProjectId returns a project ID for the subpath derived from its name,
+// or the next one not used by another directory at the root.
+func (d *Driver) chooseProjectId(root, subPath string) (uint32, error) {
+	// lfs project lists the entries of the directory as
+	// "<project ID> <flags> <path>"
+	out, err := d.executor.Run("lfs", "project", root)
+	if err != nil {
+		return 0, fmt.Errorf("could not list the project IDs of %s: %v: %s", root, err, out)
+	}
+	used := map[uint32]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if id, err := strconv.ParseUint(fields[0], 10, 32); err == nil {
+			used[uint32(id)] = true
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(subPath))
+	projectId := h.Sum32()%maxProjectId + 1
+	for used[projectId] {
+		projectId = projectId%maxProjectId + 1
+	}
+	return projectId, nil
+}
+
+// getProjectId returns the project ID of the directory, 0 if it has none.
+func (d *Driver) getProjectId(dir string) (uint32, error) {
+	out, err := d.executor.Run("lfs", "project", "-d", dir)
+	if err != nil {
+		return 0, fmt.Errorf("could not get the project ID of %s: %v: %s", dir, err, out)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("could not get the project ID of %s: unexpected output %q", dir, out)
+	}
+	id, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("could not get the project ID of %s: unexpected output %q", dir, out)
+	}
+	return uint32(id), nil
+}
+
+// getProjectQuotaUsage returns the capacity usage of the volume at the path
+// from the quota of its project, or nil if it has no project quota.
+func (d *Driver) getProjectQuotaUsage(path string) (*csi.VolumeUsage, error) {
+	projectId, err := d.getProjectId(path)
+	if err != nil || projectId == 0 {
+		return nil, err
+	}
+	out, err := d.executor.Run("lfs", "quota", "-q", "-p", strconv.FormatUint(uint64(projectId), 10), path)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the quota of project %d: %v: %s", projectId, err, out)
+	}
+	usedKiB, limitKiB, err := parseProjectQuota(string(out))
+	if err != nil {
+		return nil, fmt.Errorf("could not get the quota of project %d: %v", projectId, err)
+	}
+	if limitKiB == 0 {
+		return nil, nil
+	}
+	usage := &csi.VolumeUsage{
+		Total: limitKiB * 1024,
+		Used:  usedKiB * 1024,
+		Unit:  csi.VolumeUsage_BYTES,
+	}
+	if usage.Used < usage.Total {
+		usage.Available = usage.Total - usage.Used
+	}
+	return usage, nil
+}
+
+// parseProjectQuota returns the used space and the hard limit in KiB from
+// the output of lfs quota -q, which is the filesystem followed by the
+// kbytes, quota, limit and grace columns of space and inodes. The used
+// space is marked with a "*" when it exceeds the quota.
+func parseProjectQuota(out string) (int64, int64, error) {
+	fields := strings.Fields(out)
+	if len(fields) < 4 {
+		return 0, 0, fmt.Errorf("unexpected output %q", out)
+	}
+	used, err := strconv.ParseInt(strings.TrimSuffix(fields[1], "*"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected output %q", out)
+	}
+	limit, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected output %q", out)
+	}
+	return used, limit, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"hash/fnv"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/driver/mocks"
+)
+
+func TestChooseProjectId(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	mockExecutor := mocks.NewMockExecutor(mockCtl)
+	driver := &Driver{
+		executor: mockExecutor,
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte("pvc-1"))
+	hashed := h.Sum32()%maxProjectId + 1
+
+	// another directory already has the project ID derived from the name
+	listing := "    0 - /mnt/pvc-1\n" +
+		"  " + strconv.FormatUint(uint64(hashed), 10) + " P /mnt/pvc-2\n"
+	mockExecutor.EXPECT().Run("lfs", "project", "/mnt").Return([]byte(listing), nil)
+
+	projectId, err := driver.chooseProjectId("/mnt", "pvc-1")
+	if err != nil {
+		t.Fatalf("chooseProjectId is failed: %v", err)
+	}
+	expected := hashed%maxProjectId + 1
+	if projectId != expected {
+		t.Fatalf("Project ID mismatches. actual: %v expected: %v", projectId, expected)
+	}
+
+	mockCtl.Finish()
+}
+
+func TestParseProjectQuota(t *testing.T) {
+	testCases := []struct {
+		name          string
+		out           string
+		expectedUsed  int64
+		expectedLimit int64
+		expectError   bool
+	}{
+		{
+			name:          "success: normal",
+			out:           "      /mnt/fsx     100       0    1000       -       5       0       0       -\n",
+			expectedUsed:  100,
+			expectedLimit: 1000,
+		},
+		{
+			name:          "success: over quota with the filesystem on its own line",
+			out:           "/var/lib/kubelet/pods/1234/volumes/kubernetes.io~csi/pvc-1/mount\n           2048*      0    1000       -       5       0       0       -\n",
+			expectedUsed:  2048,
+			expectedLimit: 1000,
+		},
+		{
+			name:        "fail: unexpected output",
+			out:         "lfs quota: cannot find project quota\n",
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			used, limit, err := parseProjectQuota(tc.out)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("parseProjectQuota is not failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProjectQuota is failed: %v", err)
+			}
+			if used != tc.expectedUsed || limit != tc.expectedLimit {
+				t.Fatalf("Quota mismatches. actual: %v/%v expected: %v/%v", used, limit, tc.expectedUsed, tc.expectedLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/descriptor"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"k8s.io/klog"
+)
+
+const strippedValue = "***stripped***"
+
+// DefaultSensitiveKeys are the keys of parameters and volume context whose
+// values are not logged by default
+var DefaultSensitiveKeys = []string{volumeParamsKmsKeyId}
+
+// sanitizer strips the values of secret fields of CSI messages, and of
+// sensitive keys of their string maps, so that they can be logged
+type sanitizer struct {
+	sensitiveKeys map[string]bool
+}
+
+func newSanitizer(sensitiveKeys []string) *sanitizer {
+	s := &sanitizer{
+		sensitiveKeys: map[string]bool{},
+	}
+	for _, key := range sensitiveKeys {
+		s.sensitiveKeys[key] = true
+	}
+	return s
+}
+
+// sanitize returns msg formatted with its secrets and sensitive values
+// stripped. Formatting is deferred until the result is printed, so that
+// requests aren't copied when they aren't logged.
+func (s *sanitizer) sanitize(msg interface{}) fmt.Stringer {
+	return &sanitizedMessage{
+		sanitizer: s,
+		msg:       msg,
+	}
+}
+
+type sanitizedMessage struct {
+	sanitizer *sanitizer
+	msg       interface{}
+}
+
+func (m *sanitizedMessage) String() string {
+	msg, ok := m.msg.(proto.Message)
+	if !ok || reflect.ValueOf(msg).IsNil() {
+		return fmt.Sprintf("%+v", m.msg)
+	}
+	stripped := proto.Clone(msg)
+	m.sanitizer.strip(reflect.ValueOf(stripped))
+	return proto.CompactTextString(stripped)
+}
+
+// strip strips the values of the secret fields and of the sensitive keys of
+// the message v points to, and of the messages it contains. Messages in
+// oneof fields are not stripped, since CSI doesn't define secrets in them.
+func (s *sanitizer) strip(v reflect.Value) {
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	secretFields := map[string]bool{}
+	if msg, ok := v.Interface().(descriptor.Message); ok {
+		_, md := descriptor.ForMessage(msg)
+		for _, field := range md.GetField() {
+			if isSecretField(field.GetOptions()) {
+				secretFields[field.GetName()] = true
+			}
+		}
+	}
+
+	elem := v.Elem()
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		secret := secretFields[protobufFieldName(elem.Type().Field(i).Tag.Get("protobuf"))]
+		switch field.Kind() {
+		case reflect.Map:
+			if field.Type().Key().Kind() == reflect.String && field.Type().Elem().Kind() == reflect.String {
+				field.Set(reflect.ValueOf(s.stripMap(field.Interface().(map[string]string), secret)))
+			}
+		case reflect.String:
+			if secret && field.Len() > 0 {
+				field.SetString(strippedValue)
+			}
+		case reflect.Ptr:
+			s.strip(field)
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				s.strip(field.Index(j))
+			}
+		}
+	}
+}
+
+// stripMap returns a copy of m with the values of sensitive keys stripped, or
+// of all keys if m is secret
+func (s *sanitizer) stripMap(m map[string]string, secret bool) map[string]string {
+	if m == nil {
+		return nil
+	}
+	stripped := make(map[string]string, len(m))
+	for key, value := range m {
+		if secret || s.sensitiveKeys[key] {
+			value = strippedValue
+		}
+		stripped[key] = value
+	}
+	return stripped
+}
+
+func isSecretField(options proto.Message) bool {
+	if options == nil || reflect.ValueOf(options).IsNil() {
+		return false
+	}
+	ext, err := proto.GetExtension(options, csi.E_CsiSecret)
+	if err != nil {
+		return false
+	}
+	secret, ok := ext.(*bool)
+	return ok && secret != nil && *secret
+}
+
+// protobufFieldName returns the name of the proto field of a struct field
+// from its protobuf tag, e.g. "bytes,5,rep,name=secrets,proto3"
+func protobufFieldName(tag string) string {
+	for _, part := range strings.Split(tag, ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+	return ""
+}
+
+// logRequest is a gRPC interceptor that logs requests with their secrets and
+// sensitive values stripped
+func (s *sanitizer) logRequest(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	klog.V(4).Infof("%s: called with args %s", path.Base(info.FullMethod), s.sanitize(req))
+	return handler(ctx, req)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"k8s.io/klog"
+)
+
+func TestSanitize(t *testing.T) {
+	testCases := []struct {
+		name      string
+		req       interface{}
+		expValues []string
+		expHidden []string
+	}{
+		{
+			name: "CreateVolume",
+			req: &csi.CreateVolumeRequest{
+				Name: "volume-name",
+				Parameters: map[string]string{
+					"subnetId": "subnet-0eabfaa81fb22bcaf",
+					"kmsKeyId": "arn:aws:kms:us-east-1:215474938041:key/48313a27-7d88-4b51-98a4-fdf5bc80dbbe",
+				},
+				Secrets: map[string]string{
+					"password": "secret-password",
+				},
+			},
+			expValues: []string{"volume-name", "subnet-0eabfaa81fb22bcaf", "kmsKeyId", "password", strippedValue},
+			expHidden: []string{"48313a27-7d88-4b51-98a4-fdf5bc80dbbe", "secret-password"},
+		},
+		{
+			name: "DeleteVolume",
+			req: &csi.DeleteVolumeRequest{
+				VolumeId: "fs-1234",
+				Secrets: map[string]string{
+					"password": "secret-password",
+				},
+			},
+			expValues: []string{"fs-1234", strippedValue},
+			expHidden: []string{"secret-password"},
+		},
+		{
+			name: "CreateSnapshot",
+			req: &csi.CreateSnapshotRequest{
+				SourceVolumeId: "fs-1234",
+				Name:           "snapshot-name",
+				Secrets: map[string]string{
+					"password": "secret-password",
+				},
+			},
+			expValues: []string{"fs-1234", "snapshot-name"},
+			expHidden: []string{"secret-password"},
+		},
+		{
+			name: "NodeStageVolume",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          "fs-1234",
+				StagingTargetPath: "/staging/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"flock"},
+						},
+					},
+				},
+				VolumeContext: map[string]string{
+					volumeContextDnsName: "fs-1234.fsx.us-east-1.amazonaws.com",
+				},
+				Secrets: map[string]string{
+					"password": "secret-password",
+				},
+			},
+			expValues: []string{"fs-1234.fsx.us-east-1.amazonaws.com", "/staging/path", "flock"},
+			expHidden: []string{"secret-password"},
+		},
+		{
+			name: "NodePublishVolume",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:   "fs-1234",
+				TargetPath: "/target/path",
+				Secrets: map[string]string{
+					"password": "secret-password",
+				},
+			},
+			expValues: []string{"/target/path"},
+			expHidden: []string{"secret-password"},
+		},
+		{
+			name: "ControllerExpandVolume",
+			req: &csi.ControllerExpandVolumeRequest{
+				VolumeId: "fs-1234",
+				Secrets: map[string]string{
+					"password": "secret-password",
+				},
+			},
+			expValues: []string{"fs-1234"},
+			expHidden: []string{"secret-password"},
+		},
+		{
+			name:      "nil request",
+			req:       (*csi.DeleteVolumeRequest)(nil),
+			expValues: []string{"nil"},
+		},
+	}
+
+	s := newSanitizer(DefaultSensitiveKeys)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var orig proto.Message
+			if msg, ok := tc.req.(proto.Message); ok {
+				orig = proto.Clone(msg)
+			}
+
+			out := s.sanitize(tc.req).String()
+
+			for _, value := range tc.expValues {
+				if !strings.Contains(out, value) {
+					t.Fatalf("Expected %q in %q", value, out)
+				}
+			}
+			for _, value := range tc.expHidden {
+				if strings.Contains(out, value) {
+					t.Fatalf("Expected %q to be stripped from %q", value, out)
+				}
+			}
+			if orig != nil && !proto.Equal(orig, tc.req.(proto.Message)) {
+				t.Fatalf("Expected request not to be modified, got %v", tc.req)
+			}
+		})
+	}
+}
+
+func TestSanitizeSensitiveKeys(t *testing.T) {
+	req := &csi.CreateVolumeRequest{
+		Name: "volume-name",
+		Parameters: map[string]string{
+			"kmsKeyId": "key-id",
+			"token":    "token-value",
+		},
+	}
+
+	out := newSanitizer([]string{"token"}).sanitize(req).String()
+	if !strings.Contains(out, "key-id") {
+		t.Fatalf("Expected %q in %q", "key-id", out)
+	}
+	if strings.Contains(out, "token-value") {
+		t.Fatalf("Expected %q to be stripped from %q", "token-value", out)
+	}
+}
+
+func TestLogRequest(t *testing.T) {
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	if err := flags.Parse([]string{"-v=4", "-logtostderr=false"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() {
+		if err := flags.Parse([]string{"-v=0", "-logtostderr=true"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		klog.SetOutput(os.Stderr)
+	}()
+	var buf bytes.Buffer
+	klog.SetOutput(&buf)
+
+	req := &csi.NodeStageVolumeRequest{
+		VolumeId: "fs-1234",
+		Secrets: map[string]string{
+			"password": "secret-password",
+		},
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Node/NodeStageVolume"}
+	handled := false
+	_, err := newSanitizer(DefaultSensitiveKeys).logRequest(context.Background(), req, info, func(ctx context.Context, r interface{}) (interface{}, error) {
+		handled = r == req
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	klog.Flush()
+
+	if !handled {
+		t.Fatalf("Expected the request to be handled")
+	}
+	out := buf.String()
+	if !strings.Contains(out, "NodeStageVolume: called with args") {
+		t.Fatalf("Expected request to be logged, got %q", out)
+	}
+	if strings.Contains(out, "secret-password") {
+		t.Fatalf("Expected secret to be stripped from %q", out)
+	}
+}
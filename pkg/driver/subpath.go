@@ -0,0 +1,233 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
+	"k8s.io/klog"
+)
+
+// The reclaim policies of the subpath of a shared volume, applied when the
+// volume is deleted
+const (
+	// subPathReclaimRetain keeps the subpath and its data
+	subPathReclaimRetain = "Retain"
+	// subPathReclaimDelete deletes the subpath and its data
+	subPathReclaimDelete = "Delete"
+	// subPathReclaimArchive renames the subpath to archived-<subpath>
+	subPathReclaimArchive = "Archive"
+)
+
+// sharedVolume is a volume carved out of an existing filesystem as one of
+// its directories. Its ID is "shared/<filesystem ID>/<subpath>", followed by
+// "/<reclaim policy>" unless the subpath is retained. The filesystem ID is
+// followed by "@<account ID>" if the filesystem is managed with the
+// credentials of secrets.
+type sharedVolume struct {
+	fileSystemId  string
+	accountId     string
+	subPath       string
+	reclaimPolicy string
+}
+
+func parseSharedVolumeId(volumeID string) (*sharedVolume, error) {
+	parts := strings.Split(volumeID, "/")
+	if (len(parts) != 3 && len(parts) != 4) || parts[0] != sharedVolumeIdPrefix || parts[1] == "" {
+		return nil, fmt.Errorf("invalid shared volume ID %q", volumeID)
+	}
+	fileSystemId, accountId := splitAccountId(parts[1])
+	v := &sharedVolume{
+		fileSystemId:  fileSystemId,
+		accountId:     accountId,
+		subPath:       parts[2],
+		reclaimPolicy: subPathReclaimRetain,
+	}
+	if err := validateSubPath(v.subPath); err != nil {
+		return nil, err
+	}
+	if len(parts) == 4 {
+		v.reclaimPolicy = parts[3]
+		if v.reclaimPolicy != subPathReclaimDelete && v.reclaimPolicy != subPathReclaimArchive {
+			return nil, fmt.Errorf("invalid reclaim policy in shared volume ID %q", volumeID)
+		}
+	}
+	return v, nil
+}
+
+func (v *sharedVolume) volumeId() string {
+	id := fmt.Sprintf("%s/%s/%s", sharedVolumeIdPrefix, joinAccountId(v.fileSystemId, v.accountId), v.subPath)
+	if v.reclaimPolicy != subPathReclaimRetain {
+		id += "/" + v.reclaimPolicy
+	}
+	return id
+}
+
+// validateSubPath checks that the subpath is a single directory of the root
+// of the filesystem, so that deleting it can't reach anything else.
+func validateSubPath(subPath string) error {
+	if subPath == "" || subPath == "." || subPath == ".." || strings.Contains(subPath, "/") {
+		return fmt.Errorf("invalid subpath %q", subPath)
+	}
+	return nil
+}
+
+// subPathOptions are the owner and mode a subpath is created with, and what
+// happens to it when its volume is deleted
+type subPathOptions struct {
+	// uid and gid are -1 to keep the owner of the driver
+	uid  int
+	gid  int
+	mode os.FileMode
+	// hasMode is whether the mode was given rather than the default one
+	hasMode       bool
+	reclaimPolicy string
+}
+
+// needsMount returns whether the controller has to mount the filesystem for
+// the subpath. Otherwise the node creates the subpath with the default owner
+// and mode when the volume is published. A subpath reclaimed by the
+// controller is created by it too, so that a controller that can't mount the
+// filesystem fails the volume at creation rather than at deletion.
+func (o *subPathOptions) needsMount(quotaBytes int64) bool {
+	return o.uid != -1 || o.gid != -1 || o.hasMode || o.reclaimPolicy != subPathReclaimRetain || quotaBytes > 0
+}
+
+func parseSubPathOptions(volumeParams map[string]string) (*subPathOptions, error) {
+	options := &subPathOptions{
+		uid:           -1,
+		gid:           -1,
+		mode:          0755,
+		reclaimPolicy: subPathReclaimRetain,
+	}
+	if val, ok := volumeParams[volumeParamsSubPathUid]; ok {
+		uid, err := strconv.Atoi(val)
+		if err != nil || uid < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number", volumeParamsSubPathUid)
+		}
+		options.uid = uid
+	}
+	if val, ok := volumeParams[volumeParamsSubPathGid]; ok {
+		gid, err := strconv.Atoi(val)
+		if err != nil || gid < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number", volumeParamsSubPathGid)
+		}
+		options.gid = gid
+	}
+	if val, ok := volumeParams[volumeParamsSubPathMode]; ok {
+		mode, err := strconv.ParseUint(val, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("%s must be an octal mode between 0 and 0777", volumeParamsSubPathMode)
+		}
+		options.mode = os.FileMode(mode)
+		options.hasMode = true
+	}
+	if val, ok := volumeParams[volumeParamsSubPathReclaimPolicy]; ok {
+		switch val {
+		case subPathReclaimRetain, subPathReclaimDelete, subPathReclaimArchive:
+			options.reclaimPolicy = val
+		default:
+			return nil, fmt.Errorf("%s must be one of %s, %s or %s", volumeParamsSubPathReclaimPolicy, subPathReclaimRetain, subPathReclaimDelete, subPathReclaimArchive)
+		}
+	}
+	return options, nil
+}
+
+// withFileSystemMounted mounts the filesystem in a temporary directory of
+// the controller and calls fn with that directory.
+func (d *Driver) withFileSystemMounted(fs *cloud.FileSystem, fn func(root string) error) error {
+	root, err := ioutil.TempDir("", fs.FileSystemId+"-")
+	if err != nil {
+		return fmt.Errorf("could not create mount point: %v", err)
+	}
+	defer os.Remove(root)
+
+	source := fmt.Sprintf("%s@tcp:/%s", fs.DnsName, fs.MountName)
+	klog.V(5).Infof("Mounting %s at %s", source, root)
+	if err := d.mounter.Mount(source, root, "lustre", nil); err != nil {
+		return fmt.Errorf("could not mount %q: %v", source, err)
+	}
+	defer func() {
+		if err := d.mounter.Unmount(root); err != nil {
+			klog.Errorf("Could not unmount %s: %v", root, err)
+		}
+	}()
+
+	return fn(root)
+}
+
+// createSubPath creates the subpath of the filesystem with the owner and mode
+// of the options, limited to quotaBytes unless it is 0. An existing subpath is
+// only given that owner, mode and quota. Nothing is done unless the subpath
+// needs the filesystem to be mounted, it is left to the node then.
+func (d *Driver) createSubPath(fs *cloud.FileSystem, subPath string, options *subPathOptions, quotaBytes int64) error {
+	if !options.needsMount(quotaBytes) {
+		klog.V(4).Infof("Subpath %s of filesystem %s is left to be created by the node", subPath, fs.FileSystemId)
+		return nil
+	}
+	return d.withFileSystemMounted(fs, func(root string) error {
+		dir := filepath.Join(root, subPath)
+		if err := os.Mkdir(dir, options.mode); err != nil && !os.IsExist(err) {
+			return fmt.Errorf("could not create subpath %q: %v", subPath, err)
+		}
+		// the mode given to mkdir is masked by the umask
+		if err := os.Chmod(dir, options.mode); err != nil {
+			return fmt.Errorf("could not set the mode of subpath %q: %v", subPath, err)
+		}
+		if options.uid != -1 || options.gid != -1 {
+			if err := os.Chown(dir, options.uid, options.gid); err != nil {
+				return fmt.Errorf("could not set the owner of subpath %q: %v", subPath, err)
+			}
+		}
+		if quotaBytes > 0 {
+			return d.setProjectQuota(root, subPath, quotaBytes)
+		}
+		return nil
+	})
+}
+
+// reclaimSubPath deletes or archives the subpath of the shared volume. A
+// subpath that doesn't exist anymore was reclaimed already.
+func (d *Driver) reclaimSubPath(fs *cloud.FileSystem, v *sharedVolume) error {
+	return d.withFileSystemMounted(fs, func(root string) error {
+		dir := filepath.Join(root, v.subPath)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			klog.V(4).Infof("Subpath %s of filesystem %s does not exist", v.subPath, fs.FileSystemId)
+			return nil
+		}
+		switch v.reclaimPolicy {
+		case subPathReclaimDelete:
+			klog.Infof("Deleting subpath %s of filesystem %s", v.subPath, fs.FileSystemId)
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("could not delete subpath %q: %v", v.subPath, err)
+			}
+		case subPathReclaimArchive:
+			archived := filepath.Join(root, "archived-"+v.subPath)
+			klog.Infof("Archiving subpath %s of filesystem %s to %s", v.subPath, fs.FileSystemId, filepath.Base(archived))
+			if err := os.Rename(dir, archived); err != nil {
+				return fmt.Errorf("could not archive subpath %q: %v", v.subPath, err)
+			}
+		}
+		return nil
+	})
+}
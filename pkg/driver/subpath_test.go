@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSharedVolumeId(t *testing.T) {
+	testCases := []struct {
+		name        string
+		volumeId    string
+		expected    *sharedVolume
+		expectError bool
+	}{
+		{
+			name:     "success: retained subpath",
+			volumeId: "shared/fs-1234/pvc-1",
+			expected: &sharedVolume{fileSystemId: "fs-1234", subPath: "pvc-1", reclaimPolicy: subPathReclaimRetain},
+		},
+		{
+			name:     "success: deleted subpath",
+			volumeId: "shared/fs-1234/pvc-1/Delete",
+			expected: &sharedVolume{fileSystemId: "fs-1234", subPath: "pvc-1", reclaimPolicy: subPathReclaimDelete},
+		},
+		{
+			name:     "success: filesystem in another account",
+			volumeId: "shared/fs-1234@123456789012/pvc-1/Delete",
+			expected: &sharedVolume{fileSystemId: "fs-1234", accountId: "123456789012", subPath: "pvc-1", reclaimPolicy: subPathReclaimDelete},
+		},
+		{
+			name:        "fail: unknown reclaim policy",
+			volumeId:    "shared/fs-1234/pvc-1/Retain",
+			expectError: true,
+		},
+		{
+			name:        "fail: subpath outside of the root",
+			volumeId:    "shared/fs-1234/../Delete",
+			expectError: true,
+		},
+		{
+			name:        "fail: missing subpath",
+			volumeId:    "shared/fs-1234",
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := parseSharedVolumeId(tc.volumeId)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("parseSharedVolumeId is not failed: %v", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSharedVolumeId is failed: %v", err)
+			}
+			if !reflect.DeepEqual(v, tc.expected) {
+				t.Fatalf("Shared volume mismatches. actual: %v expected: %v", v, tc.expected)
+			}
+			if v.volumeId() != tc.volumeId {
+				t.Fatalf("Volume ID mismatches. actual: %v expected: %v", v.volumeId(), tc.volumeId)
+			}
+		})
+	}
+}
+
+func TestParseSubPathOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		params      map[string]string
+		expected    *subPathOptions
+		expectError bool
+	}{
+		{
+			name:     "success: defaults",
+			params:   map[string]string{},
+			expected: &subPathOptions{uid: -1, gid: -1, mode: 0755, reclaimPolicy: subPathReclaimRetain},
+		},
+		{
+			name: "success: owner, mode and reclaim policy",
+			params: map[string]string{
+				volumeParamsSubPathUid:           "1000",
+				volumeParamsSubPathGid:           "2000",
+				volumeParamsSubPathMode:          "0770",
+				volumeParamsSubPathReclaimPolicy: subPathReclaimArchive,
+			},
+			expected: &subPathOptions{uid: 1000, gid: 2000, mode: 0770, hasMode: true, reclaimPolicy: subPathReclaimArchive},
+		},
+		{
+			name: "fail: mode with special bits",
+			params: map[string]string{
+				volumeParamsSubPathUid:           "1000",
+				volumeParamsSubPathGid:           "2000",
+				volumeParamsSubPathMode:          "2770",
+				volumeParamsSubPathReclaimPolicy: subPathReclaimArchive,
+			},
+			expectError: true,
+		},
+		{
+			name:        "fail: negative uid",
+			params:      map[string]string{volumeParamsSubPathUid: "-1"},
+			expectError: true,
+		},
+		{
+			name:        "fail: unknown reclaim policy",
+			params:      map[string]string{volumeParamsSubPathReclaimPolicy: "Recycle"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options, err := parseSubPathOptions(tc.params)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("parseSubPathOptions is not failed: %v", options)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSubPathOptions is failed: %v", err)
+			}
+			if !reflect.DeepEqual(options, tc.expected) {
+				t.Fatalf("Options mismatches. actual: %+v expected: %+v", options, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSubPathOptionsNeedsMount(t *testing.T) {
+	defaults := subPathOptions{uid: -1, gid: -1, mode: 0755, reclaimPolicy: subPathReclaimRetain}
+	testCases := []struct {
+		name       string
+		options    func(o *subPathOptions)
+		quotaBytes int64
+		expected   bool
+	}{
+		{
+			name:     "defaults",
+			options:  func(o *subPathOptions) {},
+			expected: false,
+		},
+		{
+			name:     "owner",
+			options:  func(o *subPathOptions) { o.uid = 1000 },
+			expected: true,
+		},
+		{
+			name:     "mode",
+			options:  func(o *subPathOptions) { o.mode, o.hasMode = 0755, true },
+			expected: true,
+		},
+		{
+			name:     "reclaim policy",
+			options:  func(o *subPathOptions) { o.reclaimPolicy = subPathReclaimDelete },
+			expected: true,
+		},
+		{
+			name:       "quota",
+			options:    func(o *subPathOptions) {},
+			quotaBytes: 1024,
+			expected:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := defaults
+			tc.options(&options)
+			if needsMount := options.needsMount(tc.quotaBytes); needsMount != tc.expected {
+				t.Fatalf("needsMount mismatches. actual: %v expected: %v", needsMount, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
+)
+
+const (
+	// keys of the parameters csi-provisioner passes with --extra-create-metadata
+	pvcNameKey      = "csi.storage.k8s.io/pvc/name"
+	pvcNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+	pvNameKey       = "csi.storage.k8s.io/pv/name"
+
+	// PVCNameTagKey, PVCNamespaceTagKey and PVNameTagKey are the keys of the
+	// tags of the PVC and PV a filesystem is created for
+	PVCNameTagKey      = "kubernetes.io/created-for/pvc/name"
+	PVCNamespaceTagKey = "kubernetes.io/created-for/pvc/namespace"
+	PVNameTagKey       = "kubernetes.io/created-for/pv/name"
+
+	// limits of tags of AWS resources
+	maxTags           = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+	awsTagPrefix      = "aws:"
+)
+
+var (
+	tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+	// metadataTagKeys maps the parameters of the created-for metadata to
+	// the keys of their tags
+	metadataTagKeys = map[string]string{
+		pvcNameKey:      PVCNameTagKey,
+		pvcNamespaceKey: PVCNamespaceTagKey,
+		pvNameKey:       PVNameTagKey,
+	}
+
+	// reservedTagKeys are the tags set by the driver itself
+	reservedTagKeys = map[string]bool{
+		cloud.VolumeNameTagKey:     true,
+		cloud.SnapshotNameTagKey:   true,
+		cloud.ExportOnDeleteTagKey: true,
+		cloud.ClusterIdTagKey:      true,
+	}
+)
+
+// ParseTags parses a comma separated list of key=value tags and validates
+// them against the limits of AWS tags
+func ParseTags(val string) (map[string]string, error) {
+	tags := map[string]string{}
+	if strings.TrimSpace(val) == "" {
+		return tags, nil
+	}
+	for _, pair := range strings.Split(val, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("tag %q is not of the form key=value", pair)
+		}
+		key := strings.TrimSpace(kv[0])
+		if _, ok := tags[key]; ok {
+			return nil, fmt.Errorf("tag %q is duplicated", key)
+		}
+		tags[key] = strings.TrimSpace(kv[1])
+	}
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// validateTags checks that the tags can be set on an AWS resource together
+// with the tags of the driver
+func validateTags(tags map[string]string) error {
+	if len(tags)+len(reservedTagKeys) > maxTags {
+		return fmt.Errorf("too many tags: %d, at most %d are allowed", len(tags), maxTags-len(reservedTagKeys))
+	}
+	for key, value := range tags {
+		if key == "" {
+			return fmt.Errorf("tag key is empty")
+		}
+		if utf8.RuneCountInString(key) > maxTagKeyLength {
+			return fmt.Errorf("tag key %q is longer than %d characters", key, maxTagKeyLength)
+		}
+		if utf8.RuneCountInString(value) > maxTagValueLength {
+			return fmt.Errorf("value of tag %q is longer than %d characters", key, maxTagValueLength)
+		}
+		if strings.HasPrefix(strings.ToLower(key), awsTagPrefix) {
+			return fmt.Errorf("tag key %q has the reserved prefix %q", key, awsTagPrefix)
+		}
+		if reservedTagKeys[key] {
+			return fmt.Errorf("tag key %q is reserved by the driver", key)
+		}
+		if !tagPattern.MatchString(key) {
+			return fmt.Errorf("tag key %q has invalid characters", key)
+		}
+		if !tagPattern.MatchString(value) {
+			return fmt.Errorf("value of tag %q has invalid characters", key)
+		}
+	}
+	return nil
+}
+
+// validateClusterId checks that the cluster ID can be the value of the
+// ownership tag of filesystems
+func validateClusterId(clusterId string) error {
+	if utf8.RuneCountInString(clusterId) > maxTagValueLength {
+		return fmt.Errorf("cluster ID %q is longer than %d characters", clusterId, maxTagValueLength)
+	}
+	if !tagPattern.MatchString(clusterId) {
+		return fmt.Errorf("cluster ID %q has invalid characters", clusterId)
+	}
+	return nil
+}
+
+// checkOwnership returns why the driver must not delete the filesystem, or
+// nil if it may. Only filesystems created by the driver, not for another
+// cluster than the one of the controller if it has a cluster ID, and without
+// a retain tag that is not false, may be deleted. Filesystems without a
+// cluster ID were created before the controller had one, so they are
+// deleted like before.
+func (d *Driver) checkOwnership(fs *cloud.FileSystem) error {
+	if _, ok := fs.Tags[cloud.VolumeNameTagKey]; !ok {
+		return fmt.Errorf("filesystem %s was not created by the driver, it has no %s tag", fs.FileSystemId, cloud.VolumeNameTagKey)
+	}
+	if d.clusterId != "" {
+		if clusterId, ok := fs.Tags[cloud.ClusterIdTagKey]; ok && clusterId != d.clusterId {
+			return fmt.Errorf("filesystem %s belongs to cluster %q", fs.FileSystemId, clusterId)
+		}
+	}
+	if val, ok := fs.Tags[cloud.RetainTagKey]; ok {
+		if retain, err := strconv.ParseBool(val); err != nil || retain {
+			return fmt.Errorf("filesystem %s is protected by its %s=%s tag", fs.FileSystemId, cloud.RetainTagKey, val)
+		}
+	}
+	return nil
+}
+
+// volumeTags returns the tags of a filesystem created with the parameters:
+// the extra tags of the driver, overridden by the tags parameter, overridden
+// by the tags of the PVC and PV the filesystem is created for
+func (d *Driver) volumeTags(volumeParams map[string]string) (map[string]string, error) {
+	tags := map[string]string{}
+	for key, value := range d.extraTags {
+		tags[key] = value
+	}
+	if val, ok := volumeParams[volumeParamsTags]; ok {
+		paramTags, err := ParseTags(val)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range paramTags {
+			tags[key] = value
+		}
+	}
+	for param, key := range metadataTagKeys {
+		if val, ok := volumeParams[param]; ok {
+			tags[key] = val
+		}
+	}
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// backupTags returns the tags of a filesystem that are copied to its
+// backups, leaving out the tags the driver sets on filesystems only and the
+// tags of AWS, which can't be set by users. It fails if there are too many
+// tags to set on a backup together with the snapshot name tag.
+func backupTags(fsTags map[string]string) (map[string]string, error) {
+	tags := map[string]string{}
+	for key, value := range fsTags {
+		if reservedTagKeys[key] || strings.HasPrefix(strings.ToLower(key), awsTagPrefix) {
+			continue
+		}
+		tags[key] = value
+	}
+	if len(tags)+1 > maxTags {
+		return nil, fmt.Errorf("too many tags to copy: %d, at most %d are allowed", len(tags), maxTags-1)
+	}
+	return tags, nil
+}
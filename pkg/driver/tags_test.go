@@ -0,0 +1,305 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/cloud"
+)
+
+func TestParseTags(t *testing.T) {
+	tooMany := make([]string, 0, maxTags)
+	for i := 0; i < maxTags; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("key%d=value", i))
+	}
+	testCases := []struct {
+		name        string
+		tags        string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name:     "success: empty",
+			tags:     "",
+			expected: map[string]string{},
+		},
+		{
+			name: "success: tags",
+			tags: "team=storage, cost-center=1234,empty=,url=https://example.com/a+b@c",
+			expected: map[string]string{
+				"team":        "storage",
+				"cost-center": "1234",
+				"empty":       "",
+				"url":         "https://example.com/a+b@c",
+			},
+		},
+		{
+			name:        "fail: missing value",
+			tags:        "team",
+			expectError: true,
+		},
+		{
+			name:        "fail: empty key",
+			tags:        "=storage",
+			expectError: true,
+		},
+		{
+			name:        "fail: duplicated key",
+			tags:        "team=storage,team=compute",
+			expectError: true,
+		},
+		{
+			name:        "fail: aws prefix",
+			tags:        "AWS:team=storage",
+			expectError: true,
+		},
+		{
+			name:        "fail: reserved key",
+			tags:        cloud.VolumeNameTagKey + "=volume",
+			expectError: true,
+		},
+		{
+			name:        "fail: invalid characters",
+			tags:        "team=storage;compute",
+			expectError: true,
+		},
+		{
+			name:        "fail: key too long",
+			tags:        strings.Repeat("k", maxTagKeyLength+1) + "=value",
+			expectError: true,
+		},
+		{
+			name:        "fail: value too long",
+			tags:        "key=" + strings.Repeat("v", maxTagValueLength+1),
+			expectError: true,
+		},
+		{
+			name:        "fail: too many tags",
+			tags:        strings.Join(tooMany, ","),
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := ParseTags(tc.tags)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("ParseTags is not failed: %v", tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTags is failed: %v", err)
+			}
+			if !reflect.DeepEqual(tags, tc.expected) {
+				t.Fatalf("Tags mismatch. actual: %v expected: %v", tags, tc.expected)
+			}
+		})
+	}
+}
+
+func TestVolumeTags(t *testing.T) {
+	testCases := []struct {
+		name        string
+		extraTags   map[string]string
+		params      map[string]string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name:     "success: no tags",
+			params:   map[string]string{},
+			expected: map[string]string{},
+		},
+		{
+			name:      "success: parameter overrides extra tags",
+			extraTags: map[string]string{"team": "default", "cluster": "prod"},
+			params:    map[string]string{volumeParamsTags: "team=storage"},
+			expected:  map[string]string{"team": "storage", "cluster": "prod"},
+		},
+		{
+			name:   "success: metadata",
+			params: map[string]string{pvcNameKey: "claim", pvcNamespaceKey: "default", pvNameKey: "pv"},
+			expected: map[string]string{
+				PVCNameTagKey:      "claim",
+				PVCNamespaceTagKey: "default",
+				PVNameTagKey:       "pv",
+			},
+		},
+		{
+			name:        "fail: invalid parameter",
+			params:      map[string]string{volumeParamsTags: "team"},
+			expectError: true,
+		},
+		{
+			name:        "fail: invalid metadata",
+			params:      map[string]string{pvcNameKey: strings.Repeat("c", maxTagValueLength+1)},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Driver{extraTags: tc.extraTags}
+			tags, err := d.volumeTags(tc.params)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("volumeTags is not failed: %v", tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("volumeTags is failed: %v", err)
+			}
+			if !reflect.DeepEqual(tags, tc.expected) {
+				t.Fatalf("Tags mismatch. actual: %v expected: %v", tags, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBackupTags(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i < maxTags; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+	testCases := []struct {
+		name        string
+		fsTags      map[string]string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name: "success: tags of the driver left out",
+			fsTags: map[string]string{
+				cloud.VolumeNameTagKey: "volume",
+				cloud.ClusterIdTagKey:  "cluster-1",
+				PVCNameTagKey:          "claim",
+				"team":                 "storage",
+			},
+			expected: map[string]string{PVCNameTagKey: "claim", "team": "storage"},
+		},
+		{
+			name: "success: tags of AWS left out",
+			fsTags: map[string]string{
+				"aws:cloudformation:stack-name": "stack",
+				"AWS:backup:source-resource":    "fs",
+				"team":                          "storage",
+			},
+			expected: map[string]string{"team": "storage"},
+		},
+		{
+			name:        "fail: too many tags",
+			fsTags:      tooMany,
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := backupTags(tc.fsTags)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("backupTags is not failed: %v", tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("backupTags is failed: %v", err)
+			}
+			if !reflect.DeepEqual(tags, tc.expected) {
+				t.Fatalf("Tags mismatch. actual: %v expected: %v", tags, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCheckOwnership(t *testing.T) {
+	testCases := []struct {
+		name        string
+		clusterId   string
+		tags        map[string]string
+		expectError bool
+	}{
+		{
+			name: "success: created by the driver",
+			tags: map[string]string{cloud.VolumeNameTagKey: "volume"},
+		},
+		{
+			name:      "success: created for the cluster",
+			clusterId: "cluster-1",
+			tags:      map[string]string{cloud.VolumeNameTagKey: "volume", cloud.ClusterIdTagKey: "cluster-1"},
+		},
+		{
+			name:      "success: created before the cluster ID was set",
+			clusterId: "cluster-1",
+			tags:      map[string]string{cloud.VolumeNameTagKey: "volume"},
+		},
+		{
+			name: "success: retain tag is false",
+			tags: map[string]string{cloud.VolumeNameTagKey: "volume", cloud.RetainTagKey: "false"},
+		},
+		{
+			name:        "fail: not created by the driver",
+			tags:        map[string]string{},
+			expectError: true,
+		},
+		{
+			name:        "fail: created for another cluster",
+			clusterId:   "cluster-1",
+			tags:        map[string]string{cloud.VolumeNameTagKey: "volume", cloud.ClusterIdTagKey: "cluster-2"},
+			expectError: true,
+		},
+		{
+			name:        "fail: retained",
+			tags:        map[string]string{cloud.VolumeNameTagKey: "volume", cloud.RetainTagKey: "true"},
+			expectError: true,
+		},
+		{
+			name:        "fail: retain tag without boolean value",
+			tags:        map[string]string{cloud.VolumeNameTagKey: "volume", cloud.RetainTagKey: ""},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Driver{clusterId: tc.clusterId}
+			err := d.checkOwnership(&cloud.FileSystem{FileSystemId: "fs-1234", Tags: tc.tags})
+			if tc.expectError && err == nil {
+				t.Fatal("checkOwnership is not failed")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("checkOwnership is failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateClusterId(t *testing.T) {
+	for _, clusterId := range []string{"", "cluster-1", "arn:aws:eks:us-west-2:123456789012:cluster/prod"} {
+		if err := validateClusterId(clusterId); err != nil {
+			t.Fatalf("validateClusterId(%q) is failed: %v", clusterId, err)
+		}
+	}
+	for _, clusterId := range []string{"cluster;1", strings.Repeat("c", maxTagValueLength+1)} {
+		if err := validateClusterId(clusterId); err == nil {
+			t.Fatalf("validateClusterId(%q) is not failed", clusterId)
+		}
+	}
+}
@@ -20,7 +20,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	sanity "github.com/kubernetes-csi/csi-test/pkg/sanity"
+	sanity "github.com/kubernetes-csi/csi-test/v4/pkg/sanity"
 
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/driver"
 	"github.com/kubernetes-sigs/aws-fsx-csi-driver/pkg/util"
@@ -56,11 +56,20 @@ var _ = AfterSuite(func() {
 var _ = Describe("AWS FSx for Lustre CSI Driver", func() {
 	_ = os.MkdirAll("/tmp/csi", os.ModePerm)
 
-	config := &sanity.Config{
-		Address:        endpoint,
-		TargetPath:     mountPath,
-		StagingPath:    stagePath,
-		TestVolumeSize: 2000 * util.GiB,
+	config := sanity.NewTestConfig()
+	config.Address = endpoint
+	config.TargetPath = mountPath
+	config.StagingPath = stagePath
+	config.TestVolumeSize = 2000 * util.GiB
+	// expanded volumes are rounded up to the SCRATCH_2 granularity, so the
+	// expanded size must be a multiple of it to be returned as requested
+	config.TestVolumeExpandSize = 4800 * util.GiB
+	// csi-test passes these parameters with every CreateVolume request. The
+	// subnet and the VPC's default security group are discovered through the
+	// fake EC2, since a security group source is required.
+	config.TestVolumeParameters = map[string]string{
+		"securityGroupNames": "default",
+		"deploymentType":     "SCRATCH_2",
 	}
-	sanity.GinkgoTest(config)
+	sanity.GinkgoTest(&config)
 })